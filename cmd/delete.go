@@ -36,6 +36,7 @@ func doDeleteCmd(cmd *cobra.Command, args []string) {
 	if err == nil {
 		log.Info().Str("app", appID).Str("keypath", keypath).Msg("Value deleted successfully")
 	} else {
+		tuiKeyPathError(keypath, err)
 		log.Fatal().Str("app", appID).Str("keypath", keypath).Err(err).Msg("Failed to delete value")
 	}
 
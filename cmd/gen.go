@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jheddings/go-cfprefs/gen"
+	"github.com/jheddings/go-cfprefs/schema"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	genSchemaFile string
+	genOutput     string
+	genOptions    gen.Options
+)
+
+var genCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate code for a preference domain",
+}
+
+var genGoCmd = &cobra.Command{
+	Use:   "go <appID>",
+	Short: "Generate Go types for a preference domain",
+	Long: `Generate Go struct types and typed keys for the specified application ID.
+
+The types are derived from the current preference values, or from a JSON Schema
+given with --schema. The generated struct can be read and written with
+cfprefs.Load and cfprefs.Save.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doGenGoCmd,
+}
+
+func init() {
+	genGoCmd.Flags().StringVar(&genSchemaFile, "schema", "", "Generate from a JSON Schema file instead of current values")
+	genGoCmd.Flags().StringVarP(&genOutput, "output", "o", "", "Write the code to a file instead of stdout")
+	genGoCmd.Flags().StringVar(&genOptions.Package, "package", "prefs", "Name of the generated package")
+	genGoCmd.Flags().StringVar(&genOptions.Type, "type", "Preferences", "Name of the generated struct")
+
+	genCmd.AddCommand(genGoCmd)
+	rootCmd.AddCommand(genCmd)
+}
+
+func doGenGoCmd(cmd *cobra.Command, args []string) {
+	appID := args[0]
+	log.Trace().Str("app", appID).Str("schema", genSchemaFile).Msg("Generating Go code")
+
+	var src []byte
+	var err error
+
+	if genSchemaFile == "" {
+		src, err = gen.GoFromDomain(appID, genOptions)
+	} else {
+		src, err = genFromSchema(appID)
+	}
+
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate code")
+	}
+
+	if genOutput == "" {
+		fmt.Print(string(src))
+		return
+	}
+
+	if err := os.WriteFile(genOutput, src, 0o644); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write generated code")
+	}
+
+	log.Info().Str("file", genOutput).Msg("Code generated successfully")
+}
+
+func genFromSchema(appID string) ([]byte, error) {
+	data, err := os.ReadFile(genSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := schema.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return gen.Go(appID, s, genOptions)
+}
@@ -9,18 +9,26 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var readTyped bool
+
 var readCmd = &cobra.Command{
 	Use:   "read <appID> [<key>]",
 	Short: "Read a preference value",
 	Long: `Read a preference value for the specified application ID.
 
 The key can be a simple name or include a JSON Pointer path (e.g., "config/server/port")
-to access nested values within the preference.`,
+to access nested values within the preference.
+
+With --typed, the value is printed with its storage kind and numeric width, so
+dates, data and number sizes are preserved when it is written back with
+"write --typed".`,
 	Args: cobra.MinimumNArgs(1),
 	Run:  doReadCmd,
 }
 
 func init() {
+	readCmd.Flags().BoolVar(&readTyped, "typed", false, "Include the storage type of each value")
+
 	rootCmd.AddCommand(readCmd)
 }
 
@@ -58,11 +66,19 @@ func doReadValueCmd(cmd *cobra.Command, args []string) {
 
 	log.Trace().Str("app", appID).Str("key", key).Msg("Reading preference")
 
-	value, err := cfprefs.Get(appID, key)
+	var value any
+	var err error
+
+	if readTyped {
+		value, err = cfprefs.GetValue(appID, key)
+	} else {
+		value, err = cfprefs.Get(appID, key)
+	}
 
 	if err == nil {
 		log.Info().Str("app", appID).Str("key", key).Type("type", value).Msg("Value read successfully")
 	} else {
+		tuiKeyPathError(key, err)
 		log.Fatal().Err(err).Msg("Failed to read preference value")
 	}
 
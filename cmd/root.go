@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/jheddings/go-cfprefs"
 	"github.com/pterm/pterm"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -20,7 +22,7 @@ var (
 var rootCmd = &cobra.Command{
 	Use:              "cfprefs",
 	Short:            "Utility wrapper for CFPreferences on macOS",
-	PersistentPreRun: initLogging,
+	PersistentPreRun: initRoot,
 }
 
 func init() {
@@ -34,6 +36,29 @@ func init() {
 
 	pFlags.BoolP("quiet", "q", false, "Only log errors and warnings (override verbose)")
 	viper.BindPFlag("quiet", pFlags.Lookup("quiet"))
+
+	pFlags.String("path-syntax", "pointer", "Keypath syntax: 'pointer' (config/items/0) or 'dot' (config.items[0])")
+	viper.BindPFlag("path-syntax", pFlags.Lookup("path-syntax"))
+}
+
+func initRoot(cmd *cobra.Command, args []string) {
+	initLogging(cmd, args)
+	initPathSyntax()
+}
+
+func initPathSyntax() {
+	syntax := viper.GetString("path-syntax")
+
+	switch strings.ToLower(syntax) {
+	case "pointer", "":
+		cfprefs.SetPathSyntax(cfprefs.PointerSyntax)
+	case "dot":
+		cfprefs.SetPathSyntax(cfprefs.DotSyntax)
+	default:
+		log.Fatal().Str("syntax", syntax).Msg("Unsupported path syntax")
+	}
+
+	log.Trace().Stringer("syntax", cfprefs.GetPathSyntax()).Msg("Using path syntax")
 }
 
 func initLogging(cmd *cobra.Command, args []string) {
@@ -78,3 +103,44 @@ func tuiConfirm(message string) {
 		os.Exit(1)
 	}
 }
+
+// tuiKeyPathError prints the keypath with a caret under the segment that
+// caused err, if err identifies one.
+func tuiKeyPathError(keypath string, err error) {
+	var kpErr *cfprefs.KeyPathErr
+	if !errors.As(err, &kpErr) || kpErr.Index < 0 {
+		return
+	}
+
+	syntax := cfprefs.GetPathSyntax()
+
+	kp, perr := cfprefs.ParseKeyPathWith(keypath, syntax)
+	if perr != nil {
+		return
+	}
+
+	tokens := kp.Tokens()
+	if kpErr.Index >= len(tokens) {
+		return
+	}
+
+	// render the keypath up to and including the failing token in the same
+	// syntax, so the caret lines up with what the user typed
+	segments := make([]any, 0, kpErr.Index+1)
+	for _, token := range tokens[:kpErr.Index+1] {
+		segments = append(segments, token)
+	}
+
+	prefix := cfprefs.Path(kp.Key(), segments[:kpErr.Index]...).StringWith(syntax)
+	through := cfprefs.Path(kp.Key(), segments...).StringWith(syntax)
+
+	// skip the separator before the failing token
+	column := len(prefix)
+	if sep := through[column]; sep == '/' || sep == '.' {
+		column++
+	}
+	width := max(len(through)-column, 1)
+
+	fmt.Fprintln(os.Stderr, kp.StringWith(syntax))
+	fmt.Fprintln(os.Stderr, strings.Repeat(" ", column)+strings.Repeat("^", width))
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jheddings/go-cfprefs/schema"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema <appID>",
+	Short: "Infer a JSON Schema from preference values",
+	Long: `Print a JSON Schema describing the current preferences of the specified
+application ID.
+
+The schema lists the type of each key, the width of numbers as their format,
+and marks every top-level key as required. It is a starting point for
+documenting or validating the domain.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doSchemaCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func doSchemaCmd(cmd *cobra.Command, args []string) {
+	appID := args[0]
+	log.Trace().Str("app", appID).Msg("Inferring schema")
+
+	snapshot, err := schema.Snapshot(appID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read preferences")
+	}
+
+	jsonBytes, err := json.MarshalIndent(schema.Infer(snapshot), "", "  ")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to marshal schema to JSON")
+	}
+
+	fmt.Println(string(jsonBytes))
+}
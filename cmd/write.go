@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"strconv"
 	"time"
 
@@ -16,6 +17,7 @@ var (
 	writeTypeFloat bool
 	writeTypeBool  bool
 	writeTypeDate  bool
+	writeTyped     bool
 )
 
 var writeCmd = &cobra.Command{
@@ -24,7 +26,10 @@ var writeCmd = &cobra.Command{
 	Long: `Write a preference value for the specified application ID.
 
 The key can be a simple name or include a JSON Pointer path (e.g.,
-"config/server/port") to access nested values within the preference.`,
+"config/server/port") to access nested values within the preference.
+
+With --typed, the value is parsed as the JSON output of "read --typed" and
+stored with its original kind and numeric width.`,
 	Args: cobra.ExactArgs(3),
 	Run:  doWriteCmd,
 }
@@ -37,6 +42,7 @@ func init() {
 	flags.BoolVar(&writeTypeFloat, "float", false, "Parse value as float")
 	flags.BoolVar(&writeTypeBool, "bool", false, "Parse value as boolean")
 	flags.BoolVar(&writeTypeDate, "date", false, "Parse value as date (ISO 8601 format)")
+	flags.BoolVar(&writeTyped, "typed", false, "Parse value as typed JSON from 'read --typed'")
 
 	rootCmd.AddCommand(writeCmd)
 }
@@ -61,6 +67,9 @@ func doWriteCmd(cmd *cobra.Command, args []string) {
 	if writeTypeDate {
 		typeCount++
 	}
+	if writeTyped {
+		typeCount++
+	}
 	if typeCount > 1 {
 		log.Fatal().Msg("Only one type flag may be specified")
 	}
@@ -74,11 +83,18 @@ func doWriteCmd(cmd *cobra.Command, args []string) {
 		Type("type", value).
 		Msg("Writing preference")
 
-	err := cfprefs.Set(appID, key, value)
+	var err error
+
+	if typed, ok := value.(cfprefs.Value); ok {
+		err = cfprefs.SetValue(appID, key, typed)
+	} else {
+		err = cfprefs.Set(appID, key, value)
+	}
 
 	if err == nil {
 		log.Info().Str("app", appID).Str("key", key).Any("value", value).Msg("Value saved successfully")
 	} else {
+		tuiKeyPathError(key, err)
 		log.Fatal().Err(err).Msg("Failed to write preference value")
 	}
 
@@ -86,6 +102,14 @@ func doWriteCmd(cmd *cobra.Command, args []string) {
 }
 
 func parseValue(valueStr string) any {
+	if writeTyped {
+		var value cfprefs.Value
+		if err := json.Unmarshal([]byte(valueStr), &value); err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse value as typed JSON")
+		}
+		return value
+	}
+
 	if writeTypeInt {
 		value, err := strconv.ParseInt(valueStr, 10, 64)
 		if err != nil {
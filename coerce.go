@@ -0,0 +1,273 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// Coercion controls how GetAs converts a stored value to the requested type.
+// Policies may be combined with "|".
+type Coercion uint8
+
+const (
+	// CoerceWidth converts between numbers of different widths, such as an
+	// int32 read as an int64. Returns a RangeErr if the value does not fit.
+	CoerceWidth Coercion = 1 << iota
+
+	// CoerceReal converts between integers and reals when the value is
+	// represented exactly, such as 3.0 read as an int64. Returns a RangeErr if
+	// precision would be lost.
+	CoerceReal
+
+	// CoerceString parses strings into numbers and booleans using the strconv
+	// package, such as "42" read as an int64.
+	CoerceString
+)
+
+const (
+	// CoerceNone requires the stored value to have exactly the requested type.
+	CoerceNone Coercion = 0
+
+	// CoerceNumbers converts between numbers without loss. This is the
+	// default policy for GetAs.
+	CoerceNumbers = CoerceWidth | CoerceReal
+)
+
+// maxExactInt is the largest integer that a float64 represents exactly.
+const maxExactInt = 1 << 53
+
+// GetAs retrieves a preference value and converts it to the type T using the
+// given coercion policy. If no policy is given, CoerceNumbers is used; multiple
+// policies are combined.
+//
+// Named types are converted from values of the same kind, so a string may be
+// read as any type whose underlying type is string. Types with a registered
+// converter are decoded using the converter.
+//
+// Example usage:
+//
+//	// read a number written as an int32 by another tool
+//	port, err := GetAs[int]("com.example.app", "config/server/port")
+//
+//	// also accept numbers stored as strings
+//	port, err := GetAs[int]("com.example.app", "port", CoerceNumbers|CoerceString)
+//
+// Returns an error if the key doesn't exist, if the value cannot be converted
+// to T, or a RangeErr if the value does not fit in T.
+func GetAs[T any](appID, keypath string, coercion ...Coercion) (_ T, err error) {
+	defer wrapError(&err, "get", appID, keypath)
+
+	var zero T
+
+	value, err := Get(appID, keypath)
+	if err != nil {
+		return zero, err
+	}
+
+	policy := CoerceNumbers
+	if len(coercion) > 0 {
+		policy = CoerceNone
+		for _, c := range coercion {
+			policy |= c
+		}
+	}
+
+	return coerce[T](appID, keypath, value, policy)
+}
+
+// GetOr retrieves a preference value like GetAs, returning def if the key or
+// path does not exist. Other errors, such as a type mismatch or a path that
+// runs through a value that is not a container, are returned.
+//
+// Example usage:
+//
+//	timeout, err := GetOr("com.example.app", "timeout", 30.0)
+func GetOr[T any](appID, keypath string, def T) (T, error) {
+	value, err := GetAs[T](appID, keypath)
+	if err != nil && classifyError(err) == KindNotFound {
+		return def, nil
+	}
+	return value, err
+}
+
+// coerce converts a value to the type T using the given policy.
+func coerce[T any](appID, keypath string, value any, policy Coercion) (T, error) {
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+
+	var result T
+	if err := coerceValue(appID, keypath, value, reflect.ValueOf(&result).Elem(), policy); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return result, nil
+}
+
+// coerceValue stores a value in the target, converting it to the type of the
+// target if the policy allows.
+func coerceValue(appID, keypath string, value any, rv reflect.Value, policy Coercion) error {
+	mismatch := func() *TypeMismatchErr {
+		return NewTypeMismatchError(rv.Interface(), value).WithKey(appID, keypath)
+	}
+
+	outOfRange := func() error {
+		return NewRangeError(value).WithKey(appID, keypath).WithMsgF("does not fit in %s", rv.Type())
+	}
+
+	if conv, ok := lookupConverter(rv.Type()); ok {
+		if err := conv.decode(value, rv); err != nil {
+			return mismatch().Wrap(err)
+		}
+		return nil
+	}
+
+	// named types are converted from values of the same kind
+	if vv := reflect.ValueOf(value); vv.IsValid() && vv.Kind() == rv.Kind() && vv.Type().ConvertibleTo(rv.Type()) {
+		rv.Set(vv.Convert(rv.Type()))
+		return nil
+	}
+
+	text, isString := value.(string)
+	isString = isString && policy&CoerceString != 0
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case isString:
+			parsed, err := strconv.ParseInt(text, 10, 64)
+			if errors.Is(err, strconv.ErrRange) {
+				return outOfRange()
+			}
+			if err != nil {
+				return mismatch().Wrap(err)
+			}
+			n = parsed
+
+		case policy.allows(value, false):
+			converted, ok := toInt64(value)
+			if !ok {
+				return outOfRange()
+			}
+			n = converted
+
+		default:
+			return mismatch()
+		}
+
+		if rv.OverflowInt(n) {
+			return outOfRange()
+		}
+		rv.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch {
+		case isString:
+			parsed, err := strconv.ParseUint(text, 10, 64)
+			if errors.Is(err, strconv.ErrRange) {
+				return outOfRange()
+			}
+			if err != nil {
+				return mismatch().Wrap(err)
+			}
+			n = parsed
+
+		case policy.allows(value, false):
+			converted, ok, negative := toUint64(value)
+			if !ok || negative {
+				return outOfRange()
+			}
+			n = converted
+
+		default:
+			return mismatch()
+		}
+
+		if rv.OverflowUint(n) {
+			return outOfRange()
+		}
+		rv.SetUint(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case isString:
+			f, err := strconv.ParseFloat(text, 64)
+			if errors.Is(err, strconv.ErrRange) {
+				return outOfRange()
+			}
+			if err != nil {
+				return mismatch().Wrap(err)
+			}
+			if rv.OverflowFloat(f) {
+				return outOfRange()
+			}
+			rv.SetFloat(f)
+			return nil
+
+		case !policy.allows(value, true):
+			return mismatch()
+		}
+
+		if f, ok := value.(float32); ok {
+			rv.SetFloat(float64(f))
+			return nil
+		}
+
+		if f, ok := value.(float64); ok {
+			if rv.OverflowFloat(f) {
+				return outOfRange()
+			}
+			rv.SetFloat(f)
+			return nil
+		}
+
+		// integers must be represented exactly by the target
+		n, ok := toInt64(value)
+		if !ok || n > maxExactInt || n < -maxExactInt {
+			return outOfRange()
+		}
+		rv.SetFloat(float64(n))
+		if rv.Float() != float64(n) {
+			return outOfRange()
+		}
+		return nil
+
+	case reflect.Bool:
+		if !isString {
+			return mismatch()
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return mismatch().Wrap(err)
+		}
+		rv.SetBool(b)
+		return nil
+	}
+
+	return mismatch()
+}
+
+// allows reports whether the policy converts a number to an integer target,
+// or to a real target if toReal is set.
+func (c Coercion) allows(value any, toReal bool) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		if toReal {
+			return c&CoerceReal != 0
+		}
+		return c&CoerceWidth != 0
+
+	case float32, float64:
+		if toReal {
+			return c&CoerceWidth != 0
+		}
+		return c&CoerceReal != 0
+	}
+	return false
+}
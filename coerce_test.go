@@ -0,0 +1,209 @@
+package cfprefs
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestCoerce(t *testing.T) {
+	type mode string
+
+	t.Run("Exact", func(t *testing.T) {
+		value, err := coerce[int64]("app", "key", int64(42), CoerceNone)
+		testutil.AssertNoError(t, err, "exact type")
+		if value != 42 {
+			t.Fatalf("expected 42, got %d", value)
+		}
+
+		_, err = coerce[int64]("app", "key", int32(42), CoerceNone)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("expected type mismatch without coercion, got %v", err)
+		}
+	})
+
+	t.Run("NamedType", func(t *testing.T) {
+		value, err := coerce[mode]("app", "key", "dark", CoerceNone)
+		testutil.AssertNoError(t, err, "named string")
+		if value != "dark" {
+			t.Fatalf("expected dark, got %q", value)
+		}
+
+		timeout, err := coerce[time.Duration]("app", "key", int64(time.Second), CoerceNone)
+		testutil.AssertNoError(t, err, "named integer")
+		if timeout != time.Second {
+			t.Fatalf("expected 1s, got %v", timeout)
+		}
+	})
+
+	t.Run("Width", func(t *testing.T) {
+		value, err := coerce[int64]("app", "key", int32(-7), CoerceWidth)
+		testutil.AssertNoError(t, err, "int32 as int64")
+		if value != -7 {
+			t.Fatalf("expected -7, got %d", value)
+		}
+
+		small, err := coerce[uint8]("app", "key", int64(200), CoerceWidth)
+		testutil.AssertNoError(t, err, "int64 as uint8")
+		if small != 200 {
+			t.Fatalf("expected 200, got %d", small)
+		}
+
+		_, err = coerce[int8]("app", "key", int64(200), CoerceWidth)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected out of range, got %v", err)
+		}
+
+		_, err = coerce[uint32]("app", "key", int64(-1), CoerceWidth)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected out of range for negative, got %v", err)
+		}
+
+		_, err = coerce[int64]("app", "key", 3.0, CoerceWidth)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("expected type mismatch for real, got %v", err)
+		}
+	})
+
+	t.Run("Real", func(t *testing.T) {
+		value, err := coerce[int64]("app", "key", 3.0, CoerceReal)
+		testutil.AssertNoError(t, err, "integral real as int64")
+		if value != 3 {
+			t.Fatalf("expected 3, got %d", value)
+		}
+
+		_, err = coerce[int64]("app", "key", 3.5, CoerceReal)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected out of range for fraction, got %v", err)
+		}
+
+		f, err := coerce[float64]("app", "key", int64(42), CoerceReal)
+		testutil.AssertNoError(t, err, "int64 as float64")
+		if f != 42 {
+			t.Fatalf("expected 42, got %f", f)
+		}
+
+		_, err = coerce[float64]("app", "key", int64(math.MaxInt64), CoerceReal)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected out of range for inexact integer, got %v", err)
+		}
+
+		_, err = coerce[float32]("app", "key", int64(1<<24+1), CoerceReal)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected out of range for float32, got %v", err)
+		}
+	})
+
+	t.Run("String", func(t *testing.T) {
+		n, err := coerce[int]("app", "key", "42", CoerceString)
+		testutil.AssertNoError(t, err, "string as int")
+		if n != 42 {
+			t.Fatalf("expected 42, got %d", n)
+		}
+
+		f, err := coerce[float64]("app", "key", "2.5", CoerceString)
+		testutil.AssertNoError(t, err, "string as float64")
+		if f != 2.5 {
+			t.Fatalf("expected 2.5, got %f", f)
+		}
+
+		b, err := coerce[bool]("app", "key", "true", CoerceString)
+		testutil.AssertNoError(t, err, "string as bool")
+		if !b {
+			t.Fatalf("expected true")
+		}
+
+		_, err = coerce[int]("app", "key", "forty-two", CoerceString)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("expected type mismatch, got %v", err)
+		}
+
+		_, err = coerce[int8]("app", "key", "1000", CoerceString)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Fatalf("expected out of range, got %v", err)
+		}
+
+		_, err = coerce[int]("app", "key", "42", CoerceNumbers)
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("expected type mismatch without string parsing, got %v", err)
+		}
+	})
+}
+
+func TestGetAs(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "getas-test", map[string]any{
+		"port":  int32(8080),
+		"ratio": int64(2),
+		"count": "12",
+	})
+	defer cleanup()
+
+	port, err := GetAs[uint16](appID, "getas-test/port")
+	testutil.AssertNoError(t, err, "port as uint16")
+	if port != 8080 {
+		t.Fatalf("expected 8080, got %d", port)
+	}
+
+	value, err := GetInt(appID, "getas-test/port")
+	testutil.AssertNoError(t, err, "GetInt on int32")
+	if value != 8080 {
+		t.Fatalf("expected 8080, got %d", value)
+	}
+
+	ratio, err := GetFloat(appID, "getas-test/ratio")
+	testutil.AssertNoError(t, err, "GetFloat on integer")
+	if ratio != 2 {
+		t.Fatalf("expected 2, got %f", ratio)
+	}
+
+	_, err = GetAs[int](appID, "getas-test/count")
+	testutil.AssertError(t, err, "string without parsing")
+
+	count, err := GetAs[int](appID, "getas-test/count", CoerceNumbers, CoerceString)
+	testutil.AssertNoError(t, err, "string with parsing")
+	if count != 12 {
+		t.Fatalf("expected 12, got %d", count)
+	}
+}
+
+func TestGetOr(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "getor-test", map[string]any{"name": "value"})
+	defer cleanup()
+
+	timeout, err := GetOr(appID, "getor-test/timeout", 30.0)
+	testutil.AssertNoError(t, err, "missing path")
+	if timeout != 30.0 {
+		t.Fatalf("expected default, got %f", timeout)
+	}
+
+	timeout, err = GetOr(appID, "getor-missing-key", 15.0)
+	testutil.AssertNoError(t, err, "missing key")
+	if timeout != 15.0 {
+		t.Fatalf("expected default, got %f", timeout)
+	}
+
+	name, err := GetOr(appID, "getor-test/name", "default")
+	testutil.AssertNoError(t, err, "existing value")
+	if name != "value" {
+		t.Fatalf("expected value, got %s", name)
+	}
+
+	// type mismatches are not replaced by the default
+	_, err = GetOr(appID, "getor-test/name", 0)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected type mismatch, got %v", err)
+	}
+
+	// neither is a path that runs through a scalar
+	_, err = GetOr(appID, "getor-test/name/first", "default")
+	if !errors.Is(err, ErrInvalidKeyPath) {
+		t.Fatalf("expected invalid keypath, got %v", err)
+	}
+}
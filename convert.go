@@ -0,0 +1,119 @@
+package cfprefs
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// converter converts values of a registered type to and from the plain types
+// understood by the preferences system.
+type converter struct {
+	encode func(any) (any, error)
+	decode func(any, reflect.Value) error
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[reflect.Type]converter)
+)
+
+// RegisterConverter registers functions that convert values of type T to and
+// from the values stored by the preferences system. Registering a type again
+// replaces its converter.
+//
+// The encode function is used by Set and the other write operations, and may
+// return any value that Set accepts, including another type with a converter.
+// Converters that lead back to a type already being converted return a
+// TypeMismatchErr. The decode function is used by GetAs,
+// Unmarshal, Load and typed keys, and receives the stored value. A registered
+// converter takes precedence over encoding.TextMarshaler and the default
+// conversions for T.
+//
+// Converters are registered for an exact type, so registering T does not
+// affect *T. Nil pointers are treated as absent and are not passed to encode.
+//
+// Example usage:
+//
+//	// store durations as a number of seconds
+//	cfprefs.RegisterConverter(
+//		func(d time.Duration) (any, error) { return d.Seconds(), nil },
+//		func(v any) (time.Duration, error) {
+//			secs, ok := v.(float64)
+//			if !ok {
+//				return 0, fmt.Errorf("expected seconds, got %T", v)
+//			}
+//			return time.Duration(secs * float64(time.Second)), nil
+//		},
+//	)
+func RegisterConverter[T any](encode func(T) (any, error), decode func(any) (T, error)) {
+	if encode == nil || decode == nil {
+		panic("cfprefs: RegisterConverter with nil function")
+	}
+
+	conv := converter{
+		encode: func(v any) (any, error) {
+			return encode(v.(T))
+		},
+		decode: func(v any, rv reflect.Value) error {
+			decoded, err := decode(v)
+			if err != nil {
+				return err
+			}
+			rv.Set(reflect.ValueOf(&decoded).Elem())
+			return nil
+		},
+	}
+
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+
+	converters[reflect.TypeFor[T]()] = conv
+}
+
+// lookupConverter returns the converter registered for a type, if any.
+func lookupConverter(t reflect.Type) (converter, bool) {
+	if t == nil {
+		return converter{}, false
+	}
+
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	conv, ok := converters[t]
+	return conv, ok
+}
+
+// encodeConverted converts a value with its registered converter and prepares
+// the result for writing. If the result has a converter of its own, it is
+// converted again, and a chain of converters that returns to a type it has
+// already converted is an error rather than an endless loop.
+func encodeConverted(appID string, kp KeyPath, node any, conv converter, policy writePolicy) (any, bool, error) {
+	seen := make(map[reflect.Type]bool)
+
+	for {
+		rv := reflect.ValueOf(node)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, true, nil
+		}
+		seen[rv.Type()] = true
+
+		encoded, err := conv.encode(node)
+		if err != nil {
+			return nil, false, NewTypeMismatchError(nil, node).WithKey(appID, kp.String()).Wrap(err)
+		}
+
+		next, ok := lookupConverter(reflect.TypeOf(encoded))
+		if !ok {
+			prepared, _, err := prepareNode(appID, kp, encoded, policy)
+			return prepared, true, err
+		}
+
+		if seen[reflect.TypeOf(encoded)] {
+			return nil, false, NewTypeMismatchError(nil, node).WithKey(appID, kp.String()).
+				Wrap(fmt.Errorf("converter for %T returned %T, which was already converted", node, encoded))
+		}
+
+		node, conv = encoded, next
+	}
+}
@@ -0,0 +1,163 @@
+package cfprefs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+// point is stored as a "x,y" string by a registered converter.
+type point struct {
+	X, Y int
+}
+
+// level implements TextMarshaler, but is stored as a number by a registered
+// converter.
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	return []byte("level-" + strconv.Itoa(int(l))), nil
+}
+
+func init() {
+	RegisterConverter(
+		func(p point) (any, error) {
+			if p.X < 0 || p.Y < 0 {
+				return nil, errors.New("negative coordinate")
+			}
+			return fmt.Sprintf("%d,%d", p.X, p.Y), nil
+		},
+		func(v any) (point, error) {
+			s, ok := v.(string)
+			if !ok {
+				return point{}, fmt.Errorf("expected string, got %T", v)
+			}
+			var p point
+			_, err := fmt.Sscanf(strings.ReplaceAll(s, ",", " "), "%d %d", &p.X, &p.Y)
+			return p, err
+		},
+	)
+
+	RegisterConverter(
+		func(l level) (any, error) { return int64(l) * 10, nil },
+		func(v any) (level, error) {
+			n, ok := v.(int64)
+			if !ok {
+				return 0, fmt.Errorf("expected int64, got %T", v)
+			}
+			return level(n / 10), nil
+		},
+	)
+}
+
+func TestConverterPrepare(t *testing.T) {
+	value, err := prepareValue("app", Path("key"), map[string]any{
+		"origin": point{X: 1, Y: 2},
+		"ptr":    &point{X: 3, Y: 4},
+		"level":  level(3),
+		"list":   []point{{X: 5, Y: 6}},
+	})
+	testutil.AssertNoError(t, err, "prepare converted values")
+
+	expected := map[string]any{
+		"origin": "1,2",
+		"ptr":    "3,4",
+		"level":  int64(30),
+		"list":   []any{"5,6"},
+	}
+	if !testutil.ValuesEqualApprox(expected, value) {
+		t.Fatalf("expected %v, got %v", expected, value)
+	}
+
+	_, err = prepareValue("app", Path("key"), point{X: -1})
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected type mismatch from encoder, got %v", err)
+	}
+}
+
+// ping and pong convert into each other, and chained converts into a point.
+type (
+	ping    int
+	pong    int
+	chained int
+)
+
+func TestConverterChain(t *testing.T) {
+	RegisterConverter(
+		func(p ping) (any, error) { return pong(p), nil },
+		func(v any) (ping, error) { return 0, errors.New("not supported") },
+	)
+	RegisterConverter(
+		func(p pong) (any, error) { return ping(p), nil },
+		func(v any) (pong, error) { return 0, errors.New("not supported") },
+	)
+	RegisterConverter(
+		func(c chained) (any, error) { return point{X: int(c), Y: int(c)}, nil },
+		func(v any) (chained, error) { return 0, errors.New("not supported") },
+	)
+
+	value, err := prepareValue("app", Path("key"), chained(2))
+	testutil.AssertNoError(t, err, "prepare chained converters")
+	if value != "2,2" {
+		t.Fatalf("expected 2,2, got %v", value)
+	}
+
+	_, err = prepareValue("app", Path("key", "loop"), ping(1))
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected type mismatch for a converter loop, got %v", err)
+	}
+
+	var tmErr *TypeMismatchErr
+	if !errors.As(err, &tmErr) || tmErr.Key != "key/loop" {
+		t.Fatalf("expected the keypath of the loop, got %v", err)
+	}
+}
+
+func TestConverterRoundTrip(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	type shape struct {
+		Origin point  `plist:"origin"`
+		Corner *point `plist:"corner"`
+		Level  level  `plist:"level"`
+	}
+
+	input := shape{Origin: point{X: 1, Y: 2}, Corner: &point{X: 3, Y: 4}, Level: 7}
+
+	cleanup := setupTest(t, appID, "converter-test", input)
+	defer cleanup()
+
+	raw, err := Get(appID, "converter-test/origin")
+	testutil.AssertNoError(t, err, "get raw origin")
+	if raw != "1,2" {
+		t.Fatalf("expected stored string 1,2, got %v", raw)
+	}
+
+	var output shape
+	err = Unmarshal(appID, "converter-test", &output)
+	testutil.AssertNoError(t, err, "unmarshal shape")
+	if output.Origin != input.Origin || *output.Corner != *input.Corner || output.Level != input.Level {
+		t.Fatalf("expected %+v, got %+v", input, output)
+	}
+
+	origin, err := GetAs[point](appID, "converter-test/origin")
+	testutil.AssertNoError(t, err, "GetAs point")
+	if origin != input.Origin {
+		t.Fatalf("expected %+v, got %+v", input.Origin, origin)
+	}
+
+	lvl, err := Key[level](appID, "converter-test/level").Get()
+	testutil.AssertNoError(t, err, "key level")
+	if lvl != 7 {
+		t.Fatalf("expected level 7, got %d", lvl)
+	}
+
+	_, err = GetAs[point](appID, "converter-test/level")
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected type mismatch from decoder, got %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package cfprefs
 
 import (
-	"github.com/go-openapi/jsonpointer"
+	"context"
+	"maps"
+	"slices"
+
 	"github.com/jheddings/go-cfprefs/internal"
 )
 
@@ -18,41 +21,78 @@ import (
 //	err := Delete("com.example.app", "config/server/port")
 //
 // Returns an error if the keypath is invalid or the value cannot be deleted.
-func Delete(appID, keypath string) error {
+func Delete(appID, keypath string) (err error) {
+	defer wrapError(&err, "delete", appID, keypath)
+
 	kp, err := parseKeypath(keypath)
 	if err != nil {
 		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
 	}
 
-	// if there is no pointer path, just delete the entire key
-	if kp.IsRoot() {
-		return internal.Delete(appID, kp.Key)
-	}
+	return DeletePath(appID, kp)
+}
+
+// DeleteContext removes a preference value like Delete, honoring cancellation
+// of ctx before reading the current value and before writing it back.
+//
+// Returns the context error if ctx is done before the value is deleted.
+func DeleteContext(ctx context.Context, appID, keypath string) (err error) {
+	defer wrapError(&err, "delete", appID, keypath)
 
-	// check if the key exists
-	exists, err := internal.Exists(appID, kp.Key)
+	kp, err := parseKeypath(keypath)
 	if err != nil {
-		return NewInternalError().Wrap(err).WithMsgF("failed to check: %s", kp.Key)
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
 	}
 
-	// if key doesn't exist, return success (idempotent)
-	if !exists {
-		return nil
+	return deletePath(ctx, appID, kp)
+}
+
+// DeletePath removes a preference value at the given KeyPath and application ID.
+//
+// Example usage:
+//
+//	err := DeletePath("com.example.app", Path("config", "servers", 0))
+//
+// Returns an error if the value cannot be deleted.
+func DeletePath(appID string, kp KeyPath) (err error) {
+	defer wrapError(&err, "delete", appID, kp.String())
+
+	return deletePath(context.Background(), appID, kp)
+}
+
+// deletePath removes the value for a KeyPath, honoring cancellation of ctx.
+func deletePath(ctx context.Context, appID string, kp KeyPath) error {
+	op := WriteOp{AppID: appID, KeyPath: kp}
+	read := func() (any, bool, error) { return readRoot(ctx, appID, kp.Key()) }
+
+	return runWrite(ctx, op, read, func() error {
+		return removeValue(ctx, appID, kp)
+	})
+}
+
+// removeValue removes the value for a KeyPath, honoring cancellation of ctx.
+func removeValue(ctx context.Context, appID string, kp KeyPath) error {
+	// if there is no pointer path, just delete the entire key
+	if kp.IsRoot() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return internal.Delete(appID, kp.Key())
 	}
 
 	// get the current value
-	root, err := internal.Get(appID, kp.Key)
+	root, exists, err := readRoot(ctx, appID, kp.Key())
 	if err != nil {
-		return NewInternalError().Wrap(err).WithMsgF("failed to get: %s", kp.Key)
+		return err
 	}
 
-	ptr, err := jsonpointer.New(kp.Path)
-	if err != nil {
-		return NewKeyPathError().Wrap(err).WithMsgF("invalid path: %s", kp.Path)
+	// if key doesn't exist, return success (idempotent)
+	if !exists {
+		return nil
 	}
 
 	// delete the value at the specified path
-	modified, deleted, err := deleteValueAtPath(root, ptr.DecodedTokens())
+	modified, deleted, err := deleteValueAtPath(root, kp.tokens)
 	if err != nil {
 		return err
 	}
@@ -63,10 +103,14 @@ func Delete(appID, keypath string) error {
 	}
 
 	// otherwise, write the modified data back
-	return internal.Set(appID, kp.Key, modified)
+	return writeRoot(ctx, appID, kp.Key(), modified)
 }
 
 // deleteValueAtPath uses a pointer walker to delete a value at the specified path.
+//
+// The root is not modified. Instead, a new root is returned that shares all
+// unchanged subtrees with the original and copies only the containers along
+// the modified path. If nothing is deleted, the original root is returned.
 func deleteValueAtPath(root any, tokens []string) (any, bool, error) {
 	var modified bool
 	var walker *pointerWalker
@@ -75,7 +119,9 @@ func deleteValueAtPath(root any, tokens []string) (any, bool, error) {
 		onArrayIndex: func(arr []any, index int, remaining []string) (any, error) {
 			// if this is the last token, drop the element at the index
 			if len(remaining) == 0 {
-				result := append(arr[:index], arr[index+1:]...)
+				result := make([]any, 0, len(arr)-1)
+				result = append(result, arr[:index]...)
+				result = append(result, arr[index+1:]...)
 				modified = true
 				return result, nil
 			}
@@ -86,9 +132,15 @@ func deleteValueAtPath(root any, tokens []string) (any, bool, error) {
 				return nil, err
 			}
 
-			// update the array with any modifications
-			arr[index] = data
-			return arr, nil
+			// leave the array untouched if nothing was deleted
+			if !modified {
+				return arr, nil
+			}
+
+			// update a copy of the array with any modifications
+			result := slices.Clone(arr)
+			result[index] = data
+			return result, nil
 		},
 		onObjectKey: func(obj map[string]any, key string, remaining []string) (any, error) {
 			// if the key doesn't exist, that's ok (idempotent)
@@ -99,9 +151,10 @@ func deleteValueAtPath(root any, tokens []string) (any, bool, error) {
 
 			// if this is the last token, delete the key
 			if len(remaining) == 0 {
-				delete(obj, key)
+				result := maps.Clone(obj)
+				delete(result, key)
 				modified = true
-				return obj, nil
+				return result, nil
 			}
 
 			// recursively delete in the child
@@ -110,9 +163,15 @@ func deleteValueAtPath(root any, tokens []string) (any, bool, error) {
 				return nil, err
 			}
 
-			// update the object with any modifications
-			obj[key] = data
-			return obj, nil
+			// leave the object untouched if nothing was deleted
+			if !modified {
+				return obj, nil
+			}
+
+			// update a copy of the object with any modifications
+			result := maps.Clone(obj)
+			result[key] = data
+			return result, nil
 		},
 		onMissingElement: func(token string) (any, error) {
 			return nil, NewKeyPathError().WithMsg("path not found")
@@ -120,6 +179,6 @@ func deleteValueAtPath(root any, tokens []string) (any, bool, error) {
 	}
 
 	walker = newPointerWalker(&handler)
-	data, err := walker.walk(root, tokens)
+	data, err := walker.walkPath(root, tokens)
 	return data, modified, err
 }
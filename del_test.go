@@ -1,6 +1,8 @@
 package cfprefs
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -217,3 +219,23 @@ func TestDeleteMissingKey(t *testing.T) {
 	err := Delete(appID, "nonexistent-key")
 	testutil.AssertNoError(t, err, "delete missing key should be idempotent")
 }
+
+func TestDeleteContext(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "ctx-del-test", map[string]any{"name": "value"})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := DeleteContext(ctx, appID, "ctx-del-test/name")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	assertKeyExists(t, appID, "ctx-del-test/name", true)
+
+	err = DeleteContext(context.Background(), appID, "ctx-del-test/name")
+	testutil.AssertNoError(t, err, "delete with context")
+	assertKeyExists(t, appID, "ctx-del-test/name", false)
+}
@@ -0,0 +1,314 @@
+package cfprefs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jheddings/go-cfprefs/internal"
+)
+
+// DomainOption configures a Domain.
+type DomainOption func(*Domain)
+
+// WithPathSyntax sets the notation used to parse string keypaths for the
+// domain, overriding the global default from SetPathSyntax.
+func WithPathSyntax(syntax PathSyntax) DomainOption {
+	return func(d *Domain) {
+		d.parse = func(keypath string) (KeyPath, error) {
+			return ParseKeyPathWith(keypath, syntax)
+		}
+	}
+}
+
+// Domain is a handle to the preferences of a single application ID.
+//
+// A Domain caches the converted value of each top-level key after it is first
+// read, so repeated nested operations on the same key do not fetch and convert
+// the entire value again. Writes made through the Domain invalidate the
+// affected keys; use Refresh or Sync to pick up changes made elsewhere.
+//
+// Values returned by a Domain are shared with its cache and must not be
+// modified. A Domain is safe for concurrent use.
+type Domain struct {
+	appID string
+	parse func(string) (KeyPath, error)
+
+	mu    sync.Mutex
+	cache map[string]any
+}
+
+// Open creates a handle to the preferences for the given appID.
+//
+// Example usage:
+//
+//	d := Open("com.example.app")
+//	host, err := d.Get("config/server/host")
+//	port, err := d.Get("config/server/port")
+func Open(appID string, opts ...DomainOption) *Domain {
+	d := &Domain{
+		appID: appID,
+		parse: parseKeypath,
+		cache: make(map[string]any),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// AppID returns the application ID for the domain.
+func (d *Domain) AppID() string {
+	return d.appID
+}
+
+// Keys retrieves all top-level keys for the domain.
+func (d *Domain) Keys() (_ []string, err error) {
+	defer wrapError(&err, "keys", d.appID, "")
+
+	return internal.GetKeys(d.appID)
+}
+
+// Get retrieves a preference value for the given keypath.
+func (d *Domain) Get(keypath string) (_ any, err error) {
+	defer wrapError(&err, "get", d.appID, keypath)
+
+	kp, err := d.parse(keypath)
+	if err != nil {
+		return nil, NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return d.GetPath(kp)
+}
+
+// GetPath retrieves a preference value for the given KeyPath.
+func (d *Domain) GetPath(kp KeyPath) (_ any, err error) {
+	defer wrapError(&err, "get", d.appID, kp.String())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	root, exists, err := d.root(kp.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, NewKeyNotFoundError(d.appID, kp.Key())
+	}
+
+	result, err := getValueAtPath(root, kp.tokens)
+	if err != nil {
+		return nil, resolveError(d.appID, kp, err)
+	}
+
+	return result, nil
+}
+
+// Exists checks if a preference value exists at the given keypath.
+func (d *Domain) Exists(keypath string) (_ bool, err error) {
+	defer wrapError(&err, "exists", d.appID, keypath)
+
+	kp, err := d.parse(keypath)
+	if err != nil {
+		return false, NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return d.ExistsPath(kp)
+}
+
+// ExistsPath checks if a preference value exists at the given KeyPath.
+func (d *Domain) ExistsPath(kp KeyPath) (_ bool, err error) {
+	defer wrapError(&err, "exists", d.appID, kp.String())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	root, exists, err := d.root(kp.Key())
+	if err != nil {
+		return false, err
+	}
+
+	// look for a quick exit
+	if !exists || kp.IsRoot() {
+		return exists, nil
+	}
+
+	_, err = getValueAtPath(root, kp.tokens)
+	return err == nil, nil
+}
+
+// Set writes a preference value for the given keypath.
+func (d *Domain) Set(keypath string, value any) (err error) {
+	defer wrapError(&err, "set", d.appID, keypath)
+
+	kp, err := d.parse(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return d.SetPath(kp, value)
+}
+
+// SetPath writes a preference value for the given KeyPath.
+func (d *Domain) SetPath(kp KeyPath, value any) (err error) {
+	defer wrapError(&err, "set", d.appID, kp.String())
+
+	value, err = prepareValue(d.appID, kp, value)
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		return d.DeletePath(kp)
+	}
+
+	op := WriteOp{AppID: d.appID, KeyPath: kp, New: value}
+
+	return runWrite(context.Background(), op, d.lockedRoot(kp.Key()), func() error {
+		return d.store(kp, value)
+	})
+}
+
+// store writes a prepared value for a KeyPath.
+func (d *Domain) store(kp KeyPath, value any) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the cached value is stale after any write attempt
+	defer delete(d.cache, kp.Key())
+
+	// if there is no pointer, just set the value
+	if kp.IsRoot() {
+		return internal.Set(d.appID, kp.Key(), value)
+	}
+
+	root, exists, err := d.root(kp.Key())
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		root = make(map[string]any)
+	}
+
+	modified, err := setValueAtPath(root, kp.tokens, value)
+	if err != nil {
+		return NewInternalError().Wrap(err).WithMsgF("failed to set: %s", kp.Pointer())
+	}
+
+	return internal.Set(d.appID, kp.Key(), modified)
+}
+
+// Delete removes the preference value at the given keypath.
+func (d *Domain) Delete(keypath string) (err error) {
+	defer wrapError(&err, "delete", d.appID, keypath)
+
+	kp, err := d.parse(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return d.DeletePath(kp)
+}
+
+// DeletePath removes the preference value at the given KeyPath.
+func (d *Domain) DeletePath(kp KeyPath) (err error) {
+	defer wrapError(&err, "delete", d.appID, kp.String())
+
+	op := WriteOp{AppID: d.appID, KeyPath: kp}
+
+	return runWrite(context.Background(), op, d.lockedRoot(kp.Key()), func() error {
+		return d.remove(kp)
+	})
+}
+
+// remove removes the value for a KeyPath.
+func (d *Domain) remove(kp KeyPath) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the cached value is stale after any write attempt
+	defer delete(d.cache, kp.Key())
+
+	// if there is no pointer path, just delete the entire key
+	if kp.IsRoot() {
+		return internal.Delete(d.appID, kp.Key())
+	}
+
+	root, exists, err := d.root(kp.Key())
+	if err != nil {
+		return err
+	}
+
+	// if key doesn't exist, return success (idempotent)
+	if !exists {
+		return nil
+	}
+
+	modified, deleted, err := deleteValueAtPath(root, kp.tokens)
+	if err != nil {
+		return err
+	}
+
+	// if nothing was deleted, return success (idempotent)
+	if !deleted {
+		return nil
+	}
+
+	return internal.Set(d.appID, kp.Key(), modified)
+}
+
+// Refresh discards all cached values, so they are read again on next use.
+func (d *Domain) Refresh() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	clear(d.cache)
+}
+
+// Sync synchronizes the domain with the preferences system, writing any
+// pending changes and reloading changes made by other processes. Cached
+// values are discarded.
+func (d *Domain) Sync() (err error) {
+	defer wrapError(&err, "sync", d.appID, "")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	clear(d.cache)
+
+	if err := internal.Synchronize(d.appID); err != nil {
+		return NewInternalError().Wrap(err).WithMsgF("failed to sync: %s", d.appID)
+	}
+
+	return nil
+}
+
+// root returns the value of a top-level key, reading it into the cache if
+// needed. Missing keys are cached as nil.
+func (d *Domain) root(key string) (any, bool, error) {
+	if value, ok := d.cache[key]; ok {
+		return value, value != nil, nil
+	}
+
+	value, exists, err := readRoot(context.Background(), d.appID, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	d.cache[key] = value
+	return value, exists, nil
+}
+
+// lockedRoot returns a function that reads the value of a top-level key like
+// root, holding the lock while reading, so hooks can use the domain.
+func (d *Domain) lockedRoot(key string) func() (any, bool, error) {
+	return func() (any, bool, error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		return d.root(key)
+	}
+}
@@ -0,0 +1,124 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestDomainOperations(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	d := Open(appID)
+
+	err := d.Set("domain-test/server/host", "localhost")
+	testutil.AssertNoError(t, err, "set nested value")
+	defer Delete(appID, "domain-test")
+
+	err = d.Set("domain-test/server/port", int64(8080))
+	testutil.AssertNoError(t, err, "set sibling value")
+
+	value, err := d.Get("domain-test/server")
+	testutil.AssertNoError(t, err, "get nested value")
+
+	expected := map[string]any{"host": "localhost", "port": int64(8080)}
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("expected %v, got %v", expected, value)
+	}
+
+	exists, err := d.Exists("domain-test/server/host")
+	testutil.AssertNoError(t, err, "check nested value")
+	if !exists {
+		t.Fatal("expected nested value to exist")
+	}
+
+	err = d.Delete("domain-test/server/host")
+	testutil.AssertNoError(t, err, "delete nested value")
+
+	exists, err = d.Exists("domain-test/server/host")
+	testutil.AssertNoError(t, err, "check deleted value")
+	if exists {
+		t.Fatal("expected nested value to be deleted")
+	}
+
+	keys, err := d.Keys()
+	testutil.AssertNoError(t, err, "get keys")
+	if !slices.Contains(keys, "domain-test") {
+		t.Fatalf("expected keys to contain 'domain-test', got %v", keys)
+	}
+
+	// writes from the package functions are visible through the store
+	value, err = Get(appID, "domain-test/server/port")
+	testutil.AssertNoError(t, err, "get written value")
+	if value != int64(8080) {
+		t.Fatalf("expected 8080, got %v", value)
+	}
+
+	_, err = d.Get("domain-test/missing")
+	testutil.AssertError(t, err, "missing value")
+	if !errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrInvalidKeyPath) {
+		t.Fatalf("expected only ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestDomainCache(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	d := Open(appID)
+
+	cleanup := setupTest(t, appID, "domain-cache", "original")
+	defer cleanup()
+
+	value, err := d.Get("domain-cache")
+	testutil.AssertNoError(t, err, "get original value")
+	if value != "original" {
+		t.Fatalf("expected 'original', got %v", value)
+	}
+
+	// changes made outside of the domain are not seen until refreshed
+	err = Set(appID, "domain-cache", "external")
+	testutil.AssertNoError(t, err, "set external value")
+
+	value, err = d.Get("domain-cache")
+	testutil.AssertNoError(t, err, "get cached value")
+	if value != "original" {
+		t.Fatalf("expected cached 'original', got %v", value)
+	}
+
+	d.Refresh()
+
+	value, err = d.Get("domain-cache")
+	testutil.AssertNoError(t, err, "get refreshed value")
+	if value != "external" {
+		t.Fatalf("expected 'external', got %v", value)
+	}
+
+	// changes made through the domain invalidate the cache
+	err = d.Set("domain-cache", "internal")
+	testutil.AssertNoError(t, err, "set through domain")
+
+	value, err = d.Get("domain-cache")
+	testutil.AssertNoError(t, err, "get updated value")
+	if value != "internal" {
+		t.Fatalf("expected 'internal', got %v", value)
+	}
+
+	err = d.Sync()
+	testutil.AssertNoError(t, err, "sync domain")
+}
+
+func TestDomainPathSyntax(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	d := Open(appID, WithPathSyntax(DotSyntax))
+
+	err := d.Set("domain-syntax.items[]", "first")
+	testutil.AssertNoError(t, err, "set with dot syntax")
+	defer Delete(appID, "domain-syntax")
+
+	value, err := d.Get("domain-syntax.items[0]")
+	testutil.AssertNoError(t, err, "get with dot syntax")
+	if value != "first" {
+		t.Fatalf("expected 'first', got %v", value)
+	}
+}
@@ -1,8 +1,10 @@
 package cfprefs
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // Sentinel errors for common failures
@@ -18,8 +20,181 @@ var (
 
 	// ErrInternal is returned when an internal error occurs
 	ErrInternal = errors.New("internal error")
+
+	// ErrTxClosed is returned when using a transaction after Commit or Rollback
+	ErrTxClosed = errors.New("transaction closed")
+
+	// ErrOutOfRange is returned when a number cannot be stored without loss
+	ErrOutOfRange = errors.New("value out of range")
+
+	// ErrInvalidValue is returned when a value is rejected by a validator
+	ErrInvalidValue = errors.New("invalid value")
+
+	// ErrNilValue is returned when an array or dictionary contains a nil value
+	ErrNilValue = errors.New("nil value in container")
 )
 
+// ErrorKind classifies the failure described by an Error
+type ErrorKind int
+
+const (
+	// KindInternal is an unexpected failure in the preferences system, such
+	// as a value that cannot be converted or a failed synchronize
+	KindInternal ErrorKind = iota
+
+	// KindNotFound is a key or path that does not exist
+	KindNotFound
+
+	// KindInvalidKeyPath is a malformed keypath, or one that conflicts with
+	// the stored value
+	KindInvalidKeyPath
+
+	// KindTypeMismatch is a value that is not of the expected type
+	KindTypeMismatch
+
+	// KindOutOfRange is a number that cannot be stored or read without loss
+	KindOutOfRange
+
+	// KindInvalidValue is a value rejected by a validator
+	KindInvalidValue
+
+	// KindNilValue is a nil value inside an array or dictionary
+	KindNilValue
+
+	// KindTxClosed is a transaction used after Commit or Rollback
+	KindTxClosed
+
+	// KindCanceled is an operation stopped by its context
+	KindCanceled
+)
+
+// String returns the name of the error kind
+func (k ErrorKind) String() string {
+	switch k {
+	case KindInternal:
+		return "internal"
+	case KindNotFound:
+		return "not found"
+	case KindInvalidKeyPath:
+		return "invalid keypath"
+	case KindTypeMismatch:
+		return "type mismatch"
+	case KindOutOfRange:
+		return "out of range"
+	case KindInvalidValue:
+		return "invalid value"
+	case KindNilValue:
+		return "nil value"
+	case KindTxClosed:
+		return "transaction closed"
+	case KindCanceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// sentinel returns the sentinel error matching the kind, if any
+func (k ErrorKind) sentinel() error {
+	switch k {
+	case KindInternal:
+		return ErrInternal
+	case KindNotFound:
+		return ErrKeyNotFound
+	case KindInvalidKeyPath:
+		return ErrInvalidKeyPath
+	case KindTypeMismatch:
+		return ErrTypeMismatch
+	case KindOutOfRange:
+		return ErrOutOfRange
+	case KindInvalidValue:
+		return ErrInvalidValue
+	case KindNilValue:
+		return ErrNilValue
+	case KindTxClosed:
+		return ErrTxClosed
+	}
+	return nil
+}
+
+// Error describes a failed operation on a preference domain.
+//
+// Every operation that reads or writes preferences returns an *Error. Kind
+// classifies the failure, and Cause holds the detailed error, such as a
+// *KeyNotFoundErr or *TypeMismatchErr, so both errors.Is with the sentinel
+// errors and errors.As with the detailed types work through an *Error.
+type Error struct {
+	Op      string
+	AppID   string
+	KeyPath string
+	Kind    ErrorKind
+	Cause   error
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	target := e.KeyPath
+	if target == "" {
+		target = e.AppID
+	}
+	return fmt.Sprintf("%s %s: %v", e.Op, target, e.Cause)
+}
+
+// Is implements support for errors.Is
+func (e *Error) Is(target error) bool {
+	sentinel := e.Kind.sentinel()
+	return sentinel != nil && target == sentinel
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// wrapError records the operation, appID and keypath of a failed operation
+// in *errp. Errors that already carry this information are left unchanged.
+func wrapError(errp *error, op, appID, keypath string) {
+	if *errp == nil {
+		return
+	}
+
+	var e *Error
+	if errors.As(*errp, &e) {
+		return
+	}
+
+	*errp = &Error{
+		Op:      op,
+		AppID:   appID,
+		KeyPath: keypath,
+		Kind:    classifyError(*errp),
+		Cause:   *errp,
+	}
+}
+
+// classifyError returns the kind of an error, preferring the most specific
+// kind when an error matches several sentinels.
+func classifyError(err error) ErrorKind {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return KindCanceled
+	case errors.Is(err, ErrTxClosed):
+		return KindTxClosed
+	case errors.Is(err, ErrNilValue):
+		return KindNilValue
+	case errors.Is(err, ErrInvalidValue):
+		return KindInvalidValue
+	case errors.Is(err, ErrOutOfRange):
+		return KindOutOfRange
+	case errors.Is(err, ErrTypeMismatch):
+		return KindTypeMismatch
+	case errors.Is(err, ErrKeyNotFound):
+		return KindNotFound
+	case errors.Is(err, ErrInvalidKeyPath):
+		return KindInvalidKeyPath
+	}
+	return KindInternal
+}
+
 // InternalErr represents an error that is internal to the library
 type InternalErr struct {
 	Err error
@@ -111,8 +286,11 @@ func (e *KeyNotFoundErr) Wrap(err error) *KeyNotFoundErr {
 	return e
 }
 
-// Unwrap returns the underlying error
+// Unwrap returns the wrapped cause, or ErrKeyNotFound if there is none
 func (e *KeyNotFoundErr) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
 	return ErrKeyNotFound
 }
 
@@ -120,11 +298,55 @@ func (e *KeyNotFoundErr) Unwrap() error {
 type KeyPathErr struct {
 	Msg string
 	Err error
+
+	// Index is the position of the failing token after the top-level key, or
+	// -1 if the error does not refer to a single token
+	Index int
+
+	// Token is the decoded token that failed
+	Token string
+
+	// Prefix is the escaped pointer of the tokens that resolved successfully
+	// before the failing token
+	Prefix string
+
+	// Actual is the kind of value found at the prefix
+	Actual Kind
+
+	// Expected is the kind of value the token requires, or InvalidKind if the
+	// token failed for another reason
+	Expected Kind
+
+	// missing is set when the token refers to a value that does not exist,
+	// rather than one the path cannot reach
+	missing bool
 }
 
 // NewKeyPathError creates a new KeyPathErr
 func NewKeyPathError() *KeyPathErr {
-	return &KeyPathErr{Err: ErrInvalidKeyPath}
+	return &KeyPathErr{Err: ErrInvalidKeyPath, Index: -1}
+}
+
+// AtToken records the failing token and the tokens before it
+func (e *KeyPathErr) AtToken(tokens []string, index int) *KeyPathErr {
+	e.Index = index
+	e.Token = tokens[index]
+	e.Prefix = KeyPath{tokens: tokens[:index]}.Pointer()
+	return e
+}
+
+// WithKinds records the kind of value found and the kind the token requires
+func (e *KeyPathErr) WithKinds(actual, expected Kind) *KeyPathErr {
+	e.Actual = actual
+	e.Expected = expected
+	return e
+}
+
+// notFound marks the failing token as referring to a value that does not
+// exist
+func (e *KeyPathErr) notFound() *KeyPathErr {
+	e.missing = true
+	return e
 }
 
 // WithMsg adds a custom message to the error
@@ -141,12 +363,27 @@ func (e *KeyPathErr) WithMsgF(format string, a ...any) *KeyPathErr {
 
 // Error returns the error message
 func (e *KeyPathErr) Error() string {
-	if e.Msg != "" {
-		return fmt.Sprintf("%s: %s", e.Msg, e.Err.Error())
+	if msg := e.detail(); msg != "" {
+		return fmt.Sprintf("%s: %s", msg, e.Err.Error())
 	}
 	return e.Err.Error()
 }
 
+// detail describes the failing token, if any, after the custom message.
+func (e *KeyPathErr) detail() string {
+	msg := e.Msg
+	if e.Index >= 0 {
+		msg = strings.TrimSpace(fmt.Sprintf("%s at segment %d %q", msg, e.Index, e.Token))
+		if e.Prefix != "" {
+			msg += " after " + e.Prefix
+		}
+		if e.Expected != InvalidKind {
+			msg += fmt.Sprintf(" (expected %s, found %s)", e.Expected, kindName(e.Actual))
+		}
+	}
+	return msg
+}
+
 // Is implements support for errors.Is
 func (e *KeyPathErr) Is(target error) bool {
 	return target == ErrInvalidKeyPath
@@ -158,9 +395,20 @@ func (e *KeyPathErr) Wrap(err error) *KeyPathErr {
 	return e
 }
 
-// Unwrap returns the underlying error
-func (e *KeyPathErr) Unwrap() error {
-	return ErrInvalidKeyPath
+// Unwrap returns ErrInvalidKeyPath and any wrapped cause
+func (e *KeyPathErr) Unwrap() []error {
+	if e.Err != nil {
+		return []error{ErrInvalidKeyPath, e.Err}
+	}
+	return []error{ErrInvalidKeyPath}
+}
+
+// pathMissing reports whether an error from resolving a keypath means only
+// that the value does not exist, rather than that the path is malformed or
+// runs through a value that is not a container.
+func pathMissing(err error) bool {
+	var kpErr *KeyPathErr
+	return errors.As(err, &kpErr) && kpErr.missing
 }
 
 // TypeMismatchErr represents a type mismatch error
@@ -179,6 +427,9 @@ func NewTypeMismatchError(expected, actual any) *TypeMismatchErr {
 
 // Error returns the error message
 func (e *TypeMismatchErr) Error() string {
+	if e.Expected == nil {
+		return fmt.Sprintf("type mismatch: %s [%s] - unsupported type %T", e.Key, e.AppID, e.Actual)
+	}
 	return fmt.Sprintf("type mismatch: %s [%s] - expected %T, got %T", e.Key, e.AppID, e.Expected, e.Actual)
 }
 
@@ -200,7 +451,137 @@ func (e *TypeMismatchErr) Wrap(err error) *TypeMismatchErr {
 	return e
 }
 
-// Unwrap returns the underlying error
+// Unwrap returns the wrapped cause, or ErrTypeMismatch if there is none
 func (e *TypeMismatchErr) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
 	return ErrTypeMismatch
 }
+
+// RangeErr represents a number that cannot be stored without loss
+type RangeErr struct {
+	AppID string
+	Key   string
+	Value any
+	Msg   string
+}
+
+// NewRangeError creates a new RangeErr for the given value
+func NewRangeError(value any) *RangeErr {
+	return &RangeErr{Value: value}
+}
+
+// WithKey adds an appID and key to the error
+func (e *RangeErr) WithKey(appID, key string) *RangeErr {
+	e.AppID = appID
+	e.Key = key
+	return e
+}
+
+// WithMsg adds a custom message to the error
+func (e *RangeErr) WithMsg(msg string) *RangeErr {
+	e.Msg = msg
+	return e
+}
+
+// WithMsgF adds a formatted custom message to the error
+func (e *RangeErr) WithMsgF(format string, a ...any) *RangeErr {
+	e.Msg = fmt.Sprintf(format, a...)
+	return e
+}
+
+// Error returns the error message
+func (e *RangeErr) Error() string {
+	if e.Msg == "" {
+		return fmt.Sprintf("value out of range: %s [%s] - %v (%T)", e.Key, e.AppID, e.Value, e.Value)
+	}
+	return fmt.Sprintf("value out of range: %s [%s] - %v (%T): %s", e.Key, e.AppID, e.Value, e.Value, e.Msg)
+}
+
+// Is implements support for errors.Is
+func (e *RangeErr) Is(target error) bool {
+	return target == ErrOutOfRange
+}
+
+// Unwrap returns the underlying error
+func (e *RangeErr) Unwrap() error {
+	return ErrOutOfRange
+}
+
+// ValidationErr represents a value rejected by a validator
+type ValidationErr struct {
+	AppID string
+	Key   string
+	Value any
+	Err   error
+}
+
+// NewValidationError creates a new ValidationErr for the given value
+func NewValidationError(value any) *ValidationErr {
+	return &ValidationErr{Value: value}
+}
+
+// WithKey adds an appID and key to the error
+func (e *ValidationErr) WithKey(appID, key string) *ValidationErr {
+	e.AppID = appID
+	e.Key = key
+	return e
+}
+
+// Wrap wraps an error with the ValidationErr
+func (e *ValidationErr) Wrap(err error) *ValidationErr {
+	e.Err = errors.Join(e.Err, err)
+	return e
+}
+
+// Error returns the error message
+func (e *ValidationErr) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("invalid value: %s [%s] - %v", e.Key, e.AppID, e.Value)
+	}
+	return fmt.Sprintf("invalid value: %s [%s] - %v: %s", e.Key, e.AppID, e.Value, e.Err.Error())
+}
+
+// Is implements support for errors.Is
+func (e *ValidationErr) Is(target error) bool {
+	return target == ErrInvalidValue
+}
+
+// Unwrap returns the error from the validator
+func (e *ValidationErr) Unwrap() error {
+	return e.Err
+}
+
+// NilValueErr represents a nil value inside an array or dictionary
+type NilValueErr struct {
+	AppID string
+	Key   string
+}
+
+// NewNilValueError creates a new NilValueErr
+func NewNilValueError() *NilValueErr {
+	return &NilValueErr{}
+}
+
+// WithKey adds an appID and the keypath of the nil value to the error
+func (e *NilValueErr) WithKey(appID, key string) *NilValueErr {
+	e.AppID = appID
+	e.Key = key
+	return e
+}
+
+// Error returns the error message
+func (e *NilValueErr) Error() string {
+	return fmt.Sprintf("nil value in container: %s [%s]", e.Key, e.AppID)
+}
+
+// Is implements support for errors.Is
+func (e *NilValueErr) Is(target error) bool {
+	return target == ErrNilValue
+}
+
+// Unwrap returns the underlying error
+func (e *NilValueErr) Unwrap() error {
+	return ErrNilValue
+}
@@ -1,9 +1,13 @@
 package cfprefs
 
 import (
+	"context"
 	"errors"
+	"math"
+	"strconv"
 	"testing"
 
+	"github.com/go-openapi/jsonpointer"
 	"github.com/jheddings/go-cfprefs/testutil"
 )
 
@@ -37,14 +41,27 @@ func TestSentinelErrors(t *testing.T) {
 	})
 
 	t.Run("KeyPathErr", func(t *testing.T) {
-		err := NewKeyPathError().Wrap(errors.New("invalid/path"))
+		cause := errors.New("invalid/path")
+		err := NewKeyPathError().Wrap(cause)
 
 		if !errors.Is(err, ErrInvalidKeyPath) {
 			t.Errorf("expected errors.Is(err, ErrInvalidKeyPath) to be true")
 		}
 
-		if unwrapped := errors.Unwrap(err); unwrapped != ErrInvalidKeyPath {
-			t.Errorf("expected Unwrap to return ErrInvalidKeyPath, got %v", unwrapped)
+		if !errors.Is(err, cause) {
+			t.Errorf("expected errors.Is to reach the wrapped cause")
+		}
+
+		// the cause of a parse failure remains reachable
+		_, perr := Unflatten(map[string]any{"no-slash": 1})
+		if !errors.Is(perr, ErrInvalidKeyPath) || !errors.Is(perr, jsonpointer.ErrPointer) {
+			t.Errorf("expected the pointer error to be reachable, got %v", perr)
+		}
+
+		var numErr *strconv.NumError
+		numCause := NewKeyPathError().Wrap(&strconv.NumError{Func: "Atoi", Num: "x", Err: strconv.ErrSyntax})
+		if !errors.As(numCause, &numErr) || numErr.Num != "x" {
+			t.Errorf("expected errors.As to reach the wrapped cause, got %v", numCause)
 		}
 
 		var kpErr *KeyPathErr
@@ -59,6 +76,22 @@ func TestSentinelErrors(t *testing.T) {
 		}
 	})
 
+	t.Run("KeyPathErr segment", func(t *testing.T) {
+		tokens := []string{"server", "a/b", "port"}
+
+		err := NewKeyPathError().AtToken(tokens, 2).WithKinds(StringKind, DictionaryKind).WithMsg("cannot traverse")
+		expected := `cannot traverse at segment 2 "port" after /server/a~1b (expected dictionary, found string): invalid key path`
+		if err.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, err.Error())
+		}
+
+		err = NewKeyPathError().AtToken(tokens, 0)
+		expected = `at segment 0 "server": invalid key path`
+		if err.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, err.Error())
+		}
+	})
+
 	t.Run("TypeMismatchErr", func(t *testing.T) {
 		err := NewTypeMismatchError(int64(0), "string value").WithKey("com.test.app", "type-key")
 
@@ -84,6 +117,46 @@ func TestSentinelErrors(t *testing.T) {
 			t.Errorf("expected fields to be accessible")
 		}
 	})
+
+	t.Run("RangeErr", func(t *testing.T) {
+		err := NewRangeError(uint64(math.MaxUint64)).WithKey("com.test.app", "counter")
+
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("expected errors.Is(err, ErrOutOfRange) to be true")
+		}
+
+		if unwrapped := errors.Unwrap(err); unwrapped != ErrOutOfRange {
+			t.Errorf("expected Unwrap to return ErrOutOfRange, got %v", unwrapped)
+		}
+
+		expected := "value out of range: counter [com.test.app] - 18446744073709551615 (uint64)"
+		if err.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, err.Error())
+		}
+
+		err = err.WithMsg("too large")
+		expected += ": too large"
+		if err.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, err.Error())
+		}
+	})
+	t.Run("ValidationErr", func(t *testing.T) {
+		cause := errors.New("must be positive")
+		err := NewValidationError(-1).WithKey("com.test.app", "count").Wrap(cause)
+
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("expected errors.Is(err, ErrInvalidValue) to be true")
+		}
+
+		if !errors.Is(err, cause) {
+			t.Errorf("expected errors.Is(err, cause) to be true")
+		}
+
+		expected := "invalid value: count [com.test.app] - -1: must be positive"
+		if err.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, err.Error())
+		}
+	})
 }
 
 func TestErrorChaining(t *testing.T) {
@@ -166,9 +239,27 @@ func TestRealWorldErrors(t *testing.T) {
 		_, err = Get(appID, "not-a-dict/nested")
 		testutil.AssertError(t, err, "accessing through non-dict")
 
-		// Should be able to detect it's a key not found error
-		if !errors.Is(err, ErrKeyNotFound) {
-			t.Errorf("expected error to match ErrKeyNotFound sentinel")
+		// a path through a scalar is broken, rather than missing
+		if !errors.Is(err, ErrInvalidKeyPath) || errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("expected error to match only the ErrInvalidKeyPath sentinel, got %v", err)
+		}
+
+		var perr *Error
+		if !errors.As(err, &perr) || perr.Kind != KindInvalidKeyPath {
+			t.Errorf("expected KindInvalidKeyPath, got %v", err)
+		}
+
+		// a missing nested key is still not found
+		_, err = Get(appID, "not-a-dict")
+		testutil.AssertNoError(t, err, "reading scalar value")
+
+		err = Set(appID, "a-dict", map[string]any{"present": 1})
+		testutil.AssertNoError(t, err, "setting dict value")
+		defer Delete(appID, "a-dict")
+
+		_, err = Get(appID, "a-dict/missing")
+		if !errors.As(err, &perr) || perr.Kind != KindNotFound {
+			t.Errorf("expected KindNotFound, got %v", err)
 		}
 	})
 
@@ -188,3 +279,110 @@ func TestRealWorldErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestError(t *testing.T) {
+	t.Run("Classify", func(t *testing.T) {
+		testCases := []struct {
+			err  error
+			kind ErrorKind
+		}{
+			{err: NewKeyNotFoundError("app", "key"), kind: KindNotFound},
+			{err: NewKeyPathError(), kind: KindInvalidKeyPath},
+			{err: NewTypeMismatchError("", 1), kind: KindTypeMismatch},
+			{err: NewRangeError(1), kind: KindOutOfRange},
+			{err: NewValidationError(1), kind: KindInvalidValue},
+			{err: NewNilValueError(), kind: KindNilValue},
+			{err: ErrTxClosed, kind: KindTxClosed},
+			{err: context.Canceled, kind: KindCanceled},
+			{err: NewInternalError().Wrap(errors.New("broken")), kind: KindInternal},
+			{err: NewInternalError().Wrap(NewKeyPathError()), kind: KindInvalidKeyPath},
+			{err: errors.New("unknown"), kind: KindInternal},
+		}
+
+		for _, tc := range testCases {
+			if kind := classifyError(tc.err); kind != tc.kind {
+				t.Errorf("expected %s for %v, got %s", tc.kind, tc.err, kind)
+			}
+		}
+	})
+
+	t.Run("Wrap", func(t *testing.T) {
+		cause := errors.New("lookup failed")
+		err := error(NewKeyNotFoundError("com.test.app", "config").Wrap(cause))
+		wrapError(&err, "get", "com.test.app", "config/port")
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Fatalf("expected *Error, got %T", err)
+		}
+
+		if e.Op != "get" || e.AppID != "com.test.app" || e.KeyPath != "config/port" || e.Kind != KindNotFound {
+			t.Errorf("unexpected error fields: %+v", e)
+		}
+
+		if !errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrInternal) {
+			t.Errorf("expected error to match only ErrKeyNotFound")
+		}
+
+		// the original cause is preserved
+		if !errors.Is(err, cause) {
+			t.Errorf("expected errors.Is(err, cause) to be true")
+		}
+
+		var knfErr *KeyNotFoundErr
+		if !errors.As(err, &knfErr) || knfErr.Key != "config" {
+			t.Errorf("expected errors.As to find *KeyNotFoundErr")
+		}
+
+		expected := "get config/port: key not found: config [com.test.app]"
+		if err.Error() != expected {
+			t.Errorf("expected error message %q, got %q", expected, err.Error())
+		}
+
+		// errors that are already wrapped are unchanged
+		wrapped := err
+		wrapError(&err, "load", "com.test.app", "")
+		if err != wrapped {
+			t.Errorf("expected wrapped error to be unchanged")
+		}
+
+		err = nil
+		wrapError(&err, "get", "com.test.app", "")
+		if err != nil {
+			t.Errorf("expected nil error to stay nil")
+		}
+	})
+
+	t.Run("Operations", func(t *testing.T) {
+		appID := "com.jheddings.cfprefs.testing.errors"
+
+		_, err := Get(appID, "missing-key/nested")
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Fatalf("expected *Error, got %T", err)
+		}
+		if e.Op != "get" || e.AppID != appID || e.KeyPath != "missing-key/nested" || e.Kind != KindNotFound {
+			t.Errorf("unexpected error fields: %+v", e)
+		}
+
+		err = Set(appID, "nil-element", []any{nil})
+		if !errors.As(err, &e) || e.Op != "set" || e.Kind != KindNilValue {
+			t.Errorf("expected set error with nil value kind, got %v", err)
+		}
+
+		tx := Begin(appID)
+		tx.Rollback()
+		err = tx.Set("key", "value")
+		if !errors.As(err, &e) || e.Kind != KindTxClosed || !errors.Is(err, ErrTxClosed) {
+			t.Errorf("expected closed transaction error, got %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = GetContext(ctx, appID, "key")
+		if !errors.As(err, &e) || e.Kind != KindCanceled || !errors.Is(err, context.Canceled) {
+			t.Errorf("expected canceled error, got %v", err)
+		}
+	})
+}
@@ -1,7 +1,8 @@
 package cfprefs
 
 import (
-	"github.com/go-openapi/jsonpointer"
+	"context"
+
 	"github.com/jheddings/go-cfprefs/internal"
 )
 
@@ -19,34 +20,71 @@ import (
 //	exists, err := Exists("com.example.app", "config/server/port")
 //
 // Returns true if the key exists, false otherwise.
-func Exists(appID, keypath string) (bool, error) {
+func Exists(appID, keypath string) (_ bool, err error) {
+	defer wrapError(&err, "exists", appID, keypath)
+
 	kp, err := parseKeypath(keypath)
 	if err != nil {
 		return false, NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
 	}
 
-	exists, err := internal.Exists(appID, kp.Key)
+	return ExistsPath(appID, kp)
+}
+
+// ExistsContext checks if a preference value exists like Exists, honoring
+// cancellation of ctx before reading the value and while converting it.
+//
+// Returns the context error if ctx is done before the check completes.
+func ExistsContext(ctx context.Context, appID, keypath string) (_ bool, err error) {
+	defer wrapError(&err, "exists", appID, keypath)
+
+	kp, err := parseKeypath(keypath)
 	if err != nil {
-		return false, NewInternalError().Wrap(err).WithMsgF("failed to check: %s", kp.Key)
+		return false, NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
 	}
 
-	// look for a quick exit
-	if !exists || kp.IsRoot() {
-		return exists, nil
+	return existsPath(ctx, appID, kp)
+}
+
+// ExistsPath checks if a preference value exists at the given KeyPath and
+// application ID.
+//
+// Example usage:
+//
+//	exists, err := ExistsPath("com.example.app", Path("config", "servers", 0))
+//
+// Returns true if the value exists, false otherwise.
+func ExistsPath(appID string, kp KeyPath) (_ bool, err error) {
+	defer wrapError(&err, "exists", appID, kp.String())
+
+	return existsPath(context.Background(), appID, kp)
+}
+
+// existsPath checks for the value of a KeyPath, honoring cancellation of ctx.
+func existsPath(ctx context.Context, appID string, kp KeyPath) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
 	}
 
-	// rebuild the pointer path
-	ptr, err := jsonpointer.New(kp.Path)
+	exists, err := internal.Exists(appID, kp.Key())
 	if err != nil {
-		return false, NewKeyPathError().Wrap(err).WithMsgF("invalid pointer: %s", kp.Path)
+		return false, NewInternalError().Wrap(err).WithMsgF("failed to check: %s", kp.Key())
+	}
+
+	// look for a quick exit
+	if !exists || kp.IsRoot() {
+		return exists, nil
 	}
 
 	// get the preference value
-	val, err := internal.Get(appID, kp.Key)
+	val, err := internal.GetContext(ctx, appID, kp.Key())
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return false, ctxErr
+		}
 		return false, NewInternalError().Wrap(err).WithMsgF("failed to get value: %s", kp)
 	}
 
-	_, _, err = ptr.Get(val)
+	_, err = getValueAtPath(val, kp.tokens)
 	return err == nil, nil
 }
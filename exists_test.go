@@ -1,6 +1,8 @@
 package cfprefs
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/jheddings/go-cfprefs/testutil"
@@ -122,3 +124,24 @@ func TestExistsNested(t *testing.T) {
 		}
 	})
 }
+
+func TestExistsContext(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "ctx-exists-test", "value")
+	defer cleanup()
+
+	exists, err := ExistsContext(context.Background(), appID, "ctx-exists-test")
+	testutil.AssertNoError(t, err, "check with context")
+	if !exists {
+		t.Fatal("expected key to exist")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = ExistsContext(ctx, appID, "ctx-exists-test")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
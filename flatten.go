@@ -0,0 +1,268 @@
+package cfprefs
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/jheddings/go-cfprefs/internal"
+)
+
+// Flatten converts a value into a map of JSON Pointer paths to leaf values.
+//
+// Paths are relative to the given value (e.g., "/config/servers/0/host"), and
+// a scalar value is returned under the empty pointer "". Empty arrays and
+// dictionaries are kept as leaves so that Unflatten can restore them.
+//
+// Example usage:
+//
+//	flat := Flatten(map[string]any{"server": map[string]any{"port": 8080}})
+//	// flat = map[string]any{"/server/port": 8080}
+func Flatten(value any) map[string]any {
+	result := make(map[string]any)
+
+	walkNode(KeyPath{}, value, 0, newWalkConfig(context.Background()), func(kp KeyPath, leaf any) bool {
+		result[kp.Pointer()] = leaf
+		return true
+	})
+
+	return result
+}
+
+// FlattenDomain converts all preferences for the given appID into a map of
+// keypaths to leaf values. Each keypath is written in the current path syntax
+// (e.g., "config/server/port"), with keys escaped or quoted as needed, so it
+// is suitable for Get and Set.
+//
+// Returns an error if the keys or values for the appID cannot be read.
+func FlattenDomain(appID string) (map[string]any, error) {
+	return FlattenDomainContext(context.Background(), appID)
+}
+
+// FlattenDomainContext flattens a domain like FlattenDomain, honoring
+// cancellation of ctx before reading each top-level key and while descending
+// into its value.
+//
+// Returns the context error if ctx is done before the domain is flattened.
+func FlattenDomainContext(ctx context.Context, appID string) (_ map[string]any, err error) {
+	defer wrapError(&err, "flatten", appID, "")
+
+	keys, err := GetKeysContext(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := newWalkConfig(ctx)
+	result := make(map[string]any)
+
+	for _, key := range keys {
+		value, err := getPath(ctx, appID, Path(key))
+		if err != nil {
+			return nil, err
+		}
+
+		walkNode(Path(key), value, 0, cfg, func(kp KeyPath, leaf any) bool {
+			result[kp.StringWith(cfg.syntax)] = leaf
+			return true
+		})
+
+		// the walk stops early only if ctx is done
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Unflatten reconstructs a value from a map of JSON Pointer paths to leaf
+// values, reversing Flatten.
+//
+// Intermediate structures are created as needed: a numeric token creates an
+// array and any other token creates a dictionary. Array indices must be
+// contiguous starting from zero.
+//
+// Returns an error if the paths are invalid or conflict with each other.
+func Unflatten(flat map[string]any) (any, error) {
+	if len(flat) == 0 {
+		return nil, nil
+	}
+
+	// the empty pointer replaces the entire value
+	if value, ok := flat[""]; ok {
+		if len(flat) > 1 {
+			return nil, NewKeyPathError().WithMsg("root value conflicts with nested paths")
+		}
+		return value, nil
+	}
+
+	type entry struct {
+		path   string
+		tokens []string
+	}
+
+	entries := make([]entry, 0, len(flat))
+	for path := range flat {
+		ptr, err := jsonpointer.New(path)
+		if err != nil {
+			return nil, NewKeyPathError().Wrap(err).WithMsgF("invalid pointer: %s", path)
+		}
+		entries = append(entries, entry{path: path, tokens: ptr.DecodedTokens()})
+	}
+
+	// array elements must be inserted in index order
+	slices.SortFunc(entries, func(a, b entry) int {
+		return compareTokens(a.tokens, b.tokens)
+	})
+
+	var root any
+	for _, e := range entries {
+		tokens, err := appendTokensFor(root, e.tokens)
+		if err != nil {
+			return nil, NewKeyPathError().Wrap(err).WithMsgF("invalid path: %s", e.path)
+		}
+
+		// the structure is built here, so it is safe to modify in place
+		root, err = setValueInPlace(root, tokens, flat[e.path])
+		if err != nil {
+			return nil, NewKeyPathError().Wrap(err).WithMsgF("conflicting path: %s", e.path)
+		}
+	}
+
+	return root, nil
+}
+
+// UnflattenDomain writes a map of keypaths to leaf values into the given
+// appID, reversing FlattenDomain. Keypaths are parsed in the current path
+// syntax. Values are grouped by their top-level key and each key is replaced
+// with the reconstructed value.
+//
+// All keypaths are validated before any values are written.
+func UnflattenDomain(appID string, flat map[string]any) (err error) {
+	defer wrapError(&err, "unflatten", appID, "")
+
+	groups := make(map[string]map[string]any)
+
+	for keypath, value := range flat {
+		kp, err := parseKeypath(keypath)
+		if err != nil {
+			return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+		}
+
+		group, ok := groups[kp.Key()]
+		if !ok {
+			group = make(map[string]any)
+			groups[kp.Key()] = group
+		}
+		group[kp.Pointer()] = value
+	}
+
+	values := make(map[string]any, len(groups))
+	for key, group := range groups {
+		value, err := Unflatten(group)
+		if err != nil {
+			return err
+		}
+
+		// check every value before writing any of them
+		value, err = prepareValue(appID, Path(key), value)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		op := WriteOp{AppID: appID, KeyPath: Path(key), New: values[key]}
+		read := func() (any, bool, error) { return readRoot(context.Background(), appID, key) }
+
+		err := runWrite(context.Background(), op, read, func() error {
+			return replaceRoot(appID, key, values[key])
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceRoot writes the value of a top-level key, or deletes the key if the
+// value is nil.
+func replaceRoot(appID, key string, value any) error {
+	// a nil value cannot be stored, so it removes the key instead
+	if value == nil {
+		if err := internal.Delete(appID, key); err != nil {
+			return NewInternalError().Wrap(err).WithMsgF("failed to delete: %s", key)
+		}
+		return nil
+	}
+
+	if err := internal.Set(appID, key, value); err != nil {
+		return NewInternalError().Wrap(err).WithMsgF("failed to set: %s", key)
+	}
+
+	return nil
+}
+
+// compareTokens orders pointer tokens so that array indices sort numerically.
+func compareTokens(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ai, aErr := strconv.Atoi(a[i])
+		bi, bErr := strconv.Atoi(b[i])
+
+		var cmp int
+		if aErr == nil && bErr == nil {
+			cmp = ai - bi
+		} else {
+			cmp = strings.Compare(a[i], b[i])
+		}
+
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	return len(a) - len(b)
+}
+
+// appendTokensFor rewrites array indices that refer to the next element of an
+// array (or the first element of a new array) as ArrayAppendOp tokens, so
+// setValueAtPath grows the array rather than failing its bounds check.
+func appendTokensFor(root any, tokens []string) ([]string, error) {
+	result := slices.Clone(tokens)
+	node := root
+
+	for i, token := range result {
+		idx, err := strconv.Atoi(token)
+		if err != nil {
+			if obj, ok := node.(map[string]any); ok {
+				node = obj[token]
+			} else {
+				node = nil
+			}
+			continue
+		}
+
+		// the walker rejects indexing a dictionary, so leave the rest as-is
+		if _, ok := node.(map[string]any); ok {
+			return result, nil
+		}
+
+		arr, _ := node.([]any)
+		switch {
+		case idx == len(arr):
+			result[i] = ArrayAppendOp
+			node = nil
+		case idx >= 0 && idx < len(arr):
+			node = arr[idx]
+		default:
+			return nil, NewKeyPathError().WithMsgF("array index is not contiguous: %d", idx)
+		}
+	}
+
+	return result, nil
+}
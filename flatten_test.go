@@ -0,0 +1,193 @@
+package cfprefs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestFlatten(t *testing.T) {
+	value := map[string]any{
+		"name": "Jane Doe",
+		"a/b":  "slash",
+		"server": map[string]any{
+			"port": int64(8080),
+		},
+		"items": []any{"first", "second"},
+		"empty": []any{},
+	}
+
+	expected := map[string]any{
+		"/name":        "Jane Doe",
+		"/a~1b":        "slash",
+		"/server/port": int64(8080),
+		"/items/0":     "first",
+		"/items/1":     "second",
+		"/empty":       []any{},
+	}
+
+	flat := Flatten(value)
+	if !reflect.DeepEqual(flat, expected) {
+		t.Fatalf("expected %v, got %v", expected, flat)
+	}
+
+	// scalars flatten to the empty pointer
+	flat = Flatten("hello")
+	if !reflect.DeepEqual(flat, map[string]any{"": "hello"}) {
+		t.Fatalf("unexpected scalar flatten: %v", flat)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		items := make([]any, 12)
+		for i := range items {
+			items[i] = int64(i)
+		}
+
+		value := map[string]any{
+			"name":  "Jane Doe",
+			"a/b":   "slash",
+			"items": items,
+			"pets": []any{
+				map[string]any{"name": "Fluffy"},
+				map[string]any{"name": "Whiskers", "tags": []any{}},
+			},
+			"empty": map[string]any{},
+		}
+
+		result, err := Unflatten(Flatten(value))
+		testutil.AssertNoError(t, err, "unflatten")
+		if !reflect.DeepEqual(result, value) {
+			t.Fatalf("expected %v, got %v", value, result)
+		}
+	})
+
+	t.Run("Root array", func(t *testing.T) {
+		result, err := Unflatten(map[string]any{"/1": "b", "/0": "a"})
+		testutil.AssertNoError(t, err, "unflatten")
+		if !reflect.DeepEqual(result, []any{"a", "b"}) {
+			t.Fatalf("unexpected result: %v", result)
+		}
+	})
+
+	t.Run("Scalar", func(t *testing.T) {
+		result, err := Unflatten(map[string]any{"": "hello"})
+		testutil.AssertNoError(t, err, "unflatten")
+		if result != "hello" {
+			t.Fatalf("unexpected result: %v", result)
+		}
+	})
+
+	t.Run("Sparse array", func(t *testing.T) {
+		_, err := Unflatten(map[string]any{"/items/0": "a", "/items/2": "c"})
+		testutil.AssertError(t, err, "sparse array")
+		if !errors.Is(err, ErrInvalidKeyPath) {
+			t.Fatalf("expected ErrInvalidKeyPath, got %v", err)
+		}
+	})
+
+	t.Run("Conflicting paths", func(t *testing.T) {
+		_, err := Unflatten(map[string]any{"/a": "scalar", "/a/b": "nested"})
+		testutil.AssertError(t, err, "conflicting paths")
+
+		_, err = Unflatten(map[string]any{"": "root", "/a": "nested"})
+		testutil.AssertError(t, err, "root conflict")
+	})
+}
+
+func TestFlattenDomain(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.flatten"
+
+	flat := map[string]any{
+		"flat-test/server/host": "localhost",
+		"flat-test/server/port": int64(8080),
+		"flat-test/items/0":     "first",
+		"flat-scalar":           "hello",
+	}
+
+	err := UnflattenDomain(appID, flat)
+	testutil.AssertNoError(t, err, "unflatten domain")
+	defer Delete(appID, "flat-test")
+	defer Delete(appID, "flat-scalar")
+
+	value, err := GetStr(appID, "flat-test/server/host")
+	testutil.AssertNoError(t, err, "get nested value")
+	if value != "localhost" {
+		t.Fatalf("expected 'localhost', got '%s'", value)
+	}
+
+	result, err := FlattenDomain(appID)
+	testutil.AssertNoError(t, err, "flatten domain")
+	if !reflect.DeepEqual(result, flat) {
+		t.Fatalf("expected %v, got %v", flat, result)
+	}
+}
+
+func TestFlattenDomainEscaping(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.flatten"
+
+	// top-level keys are escaped in pointer syntax
+	flat := map[string]any{
+		"flat~1slash/a~1b": "value",
+	}
+
+	testutil.AssertNoError(t, UnflattenDomain(appID, flat), "unflatten domain")
+	defer DeletePath(appID, Path("flat/slash"))
+
+	value, err := GetPath(appID, Path("flat/slash", "a/b"))
+	testutil.AssertNoError(t, err, "get escaped value")
+	if value != "value" {
+		t.Fatalf("expected 'value', got '%v'", value)
+	}
+
+	result, err := FlattenDomain(appID)
+	testutil.AssertNoError(t, err, "flatten domain")
+	if !reflect.DeepEqual(result, flat) {
+		t.Fatalf("expected %v, got %v", flat, result)
+	}
+
+	// and quoted in dot syntax
+	SetPathSyntax(DotSyntax)
+	defer SetPathSyntax(PointerSyntax)
+
+	result, err = FlattenDomain(appID)
+	testutil.AssertNoError(t, err, "flatten domain with dot syntax")
+
+	expected := map[string]any{"flat/slash.a/b": "value"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	testutil.AssertNoError(t, UnflattenDomain(appID, map[string]any{`flat/slash."x.y"`: 1}), "unflatten dot syntax")
+
+	value, err = GetPath(appID, Path("flat/slash", "x.y"))
+	testutil.AssertNoError(t, err, "get dotted value")
+	if !valuesEqual(value, 1) {
+		t.Fatalf("expected 1, got %v", value)
+	}
+}
+
+func TestFlattenDomainContext(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.flatten"
+
+	cleanup := setupTest(t, appID, "flat-ctx", map[string]any{"a": int64(1)})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := FlattenDomainContext(ctx, appID)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	result, err := FlattenDomainContext(context.Background(), appID)
+	testutil.AssertNoError(t, err, "flatten domain with context")
+	if !valuesEqual(result["flat-ctx/a"], 1) {
+		t.Fatalf("expected flat-ctx/a to be flattened, got %v", result)
+	}
+}
@@ -0,0 +1,374 @@
+// Package gen generates Go code for working with a preference domain.
+//
+// The generated code declares a struct describing the domain, with plist tags
+// understood by cfprefs.Load and cfprefs.Save, and a typed key from
+// cfprefs.Key for each top-level key that holds a single value.
+//
+// Example usage:
+//
+//	src, err := gen.GoFromDomain("com.example.app", gen.Options{Package: "prefs"})
+//	err = os.WriteFile("prefs.go", src, 0o644)
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jheddings/go-cfprefs"
+	"github.com/jheddings/go-cfprefs/schema"
+)
+
+// Options configures the generated code.
+type Options struct {
+	// Package is the name of the generated package. The default is "prefs".
+	Package string
+
+	// Type is the name of the struct describing the domain. It also prefixes
+	// the other generated identifiers, so several domains can share a
+	// package. The default is "Preferences".
+	Type string
+}
+
+// GoFromDomain generates Go code for a domain, using a schema inferred from
+// its current values. Every key that currently exists is treated as required.
+func GoFromDomain(appID string, opts Options) ([]byte, error) {
+	snapshot, err := schema.Snapshot(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	return Go(appID, schema.Infer(snapshot), opts)
+}
+
+// Go generates formatted Go code for a domain described by a schema.
+//
+// Each object in the schema with properties becomes a struct. Keys that are
+// not required become pointers, or use omitempty for slices and maps, so an
+// absent key is not written back as a zero value. Values that allow several
+// types, or none, use any. Numbers use the width recorded in their format.
+// Keys containing a comma or backtick cannot be named in a struct tag, so
+// they are omitted, with a comment in the struct.
+func Go(appID string, s *schema.Schema, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "prefs"
+	}
+	if opts.Type == "" {
+		opts.Type = "Preferences"
+	}
+
+	g := &generator{
+		appID:   appID,
+		opts:    opts,
+		names:   make(uniqueNames),
+		imports: make(map[string]bool),
+	}
+
+	g.names.add(opts.Type + "AppID")
+	g.names.add("Load" + opts.Type)
+
+	// the Save method shares the field names of the root struct
+	root := g.structType(opts.Type, fmt.Sprintf("describes the preferences of %s.", appID), s, "Save")
+	keys := g.keys(root, s)
+
+	src := g.source(keys)
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	return formatted, nil
+}
+
+// generator collects the declarations of the generated code.
+type generator struct {
+	appID   string
+	opts    Options
+	names   uniqueNames
+	imports map[string]bool
+	structs []*structType
+}
+
+// structType is a generated struct.
+type structType struct {
+	name    string
+	doc     string
+	fields  []structField
+	omitted []string
+}
+
+// structField is a field of a generated struct.
+type structField struct {
+	name   string
+	key    string
+	goType string
+	base   string
+	doc    string
+	tag    string
+}
+
+// typedKey is a generated typed key for a top-level preference.
+type typedKey struct {
+	name   string
+	key    string
+	goType string
+	def    string
+	doc    string
+}
+
+// structType declares a struct for an object schema and returns it. Fields
+// are not given any of the reserved names, which are used by methods.
+func (g *generator) structType(name, doc string, s *schema.Schema, reserved ...string) *structType {
+	st := &structType{name: g.names.add(name), doc: doc}
+	g.structs = append(g.structs, st)
+
+	fields := make(uniqueNames)
+	for _, name := range reserved {
+		fields.add(name)
+	}
+	for _, key := range slices.Sorted(maps.Keys(s.Properties)) {
+		if !taggable(key) {
+			st.omitted = append(st.omitted, key)
+			continue
+		}
+
+		prop := s.Properties[key]
+		fieldName := fields.add(exportedName(key))
+
+		base := g.goType(prop, st.name+fieldName, "the value of "+key)
+		field := structField{name: fieldName, key: key, goType: base, base: base, tag: key}
+
+		if !slices.Contains(s.Required, key) {
+			if nillable(base) {
+				field.tag += ",omitempty"
+			} else {
+				field.goType = "*" + base
+			}
+		}
+
+		if prop != nil {
+			field.doc = prop.Description
+		}
+
+		st.fields = append(st.fields, field)
+	}
+
+	return st
+}
+
+// goType returns the Go type for values described by a schema, declaring
+// structs as needed. The name is used for a declared struct, and desc
+// describes the value in its comment.
+func (g *generator) goType(s *schema.Schema, name, desc string) string {
+	if s == nil || len(s.Type) != 1 {
+		return "any"
+	}
+
+	switch s.Type[0] {
+	case schema.TypeString:
+		return "string"
+
+	case schema.TypeBoolean:
+		return "bool"
+
+	case schema.TypeInteger:
+		switch s.Format {
+		case "int8", "int16", "int32":
+			return s.Format
+		}
+		return "int64"
+
+	case schema.TypeNumber:
+		if s.Format == "float32" {
+			return "float32"
+		}
+		return "float64"
+
+	case schema.TypeDate:
+		g.imports["time"] = true
+		return "time.Time"
+
+	case schema.TypeData:
+		return "[]byte"
+
+	case schema.TypeArray:
+		return "[]" + g.goType(s.Items, name+"Item", "an item of "+strings.TrimPrefix(desc, "the value of "))
+
+	case schema.TypeObject:
+		if len(s.Properties) > 0 {
+			return g.structType(name, fmt.Sprintf("describes %s.", desc), s).name
+		}
+
+		// dictionaries with arbitrary keys become maps
+		elem := s.AdditionalProperties
+		if elem == nil && len(s.PatternProperties) == 1 {
+			for _, pattern := range s.PatternProperties {
+				elem = pattern
+			}
+		}
+		return "map[string]" + g.goType(elem, name+"Value", "a value in "+strings.TrimPrefix(desc, "the value of "))
+	}
+
+	return "any"
+}
+
+// taggable reports whether a key can be used as the name in a struct tag.
+// Commas separate tag options, and backticks end the raw string of the tag.
+func taggable(key string) bool {
+	return !strings.ContainsAny(key, ",`")
+}
+
+// nillable reports whether a Go type already has a nil value.
+func nillable(goType string) bool {
+	return goType == "any" || strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[")
+}
+
+// keys returns the typed keys for the top-level fields that hold a single
+// value. Typed keys are not generated for arrays and dictionaries, which are
+// read through the struct, or for keys that cannot be written as a keypath.
+func (g *generator) keys(root *structType, s *schema.Schema) []typedKey {
+	var keys []typedKey
+
+	for _, field := range root.fields {
+		if !scalar(field.base) || !plainKey(field.key) {
+			continue
+		}
+
+		key := typedKey{
+			name:   g.names.add(root.name + field.name + "Key"),
+			key:    field.key,
+			goType: field.base,
+			doc:    field.doc,
+		}
+
+		if prop := s.Properties[field.key]; prop != nil {
+			key.def = literal(prop.Default, field.base)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// scalar reports whether a Go type holds a single value that cfprefs.Key
+// can convert.
+func scalar(goType string) bool {
+	switch goType {
+	case "string", "bool", "int8", "int16", "int32", "int64", "float32", "float64", "time.Time", "[]byte", "any":
+		return true
+	}
+	return false
+}
+
+// plainKey reports whether a top-level key is parsed as itself in every path
+// syntax, so it can be used as a keypath.
+func plainKey(key string) bool {
+	for _, syntax := range []cfprefs.PathSyntax{cfprefs.PointerSyntax, cfprefs.DotSyntax} {
+		kp, err := cfprefs.ParseKeyPathWith(key, syntax)
+		if err != nil || kp.Key() != key || !kp.IsRoot() {
+			return false
+		}
+	}
+	return true
+}
+
+// literal returns a Go literal for a default value of the given type, or an
+// empty string if the value cannot be written as one.
+func literal(value any, goType string) string {
+	switch v := value.(type) {
+	case string:
+		if goType == "string" {
+			return strconv.Quote(v)
+		}
+
+	case bool:
+		if goType == "bool" {
+			return strconv.FormatBool(v)
+		}
+
+	case json.Number:
+		switch goType {
+		case "int8", "int16", "int32", "int64":
+			bits, _ := strconv.Atoi(strings.TrimPrefix(goType, "int"))
+			if _, err := strconv.ParseInt(v.String(), 10, bits); err == nil {
+				return v.String()
+			}
+		case "float32", "float64":
+			bits, _ := strconv.Atoi(strings.TrimPrefix(goType, "float"))
+			if _, err := strconv.ParseFloat(v.String(), bits); err == nil {
+				return v.String()
+			}
+		}
+	}
+
+	return ""
+}
+
+// source writes the unformatted Go code.
+func (g *generator) source(keys []typedKey) []byte {
+	var sb strings.Builder
+	typ := g.opts.Type
+
+	sb.WriteString("// Code generated by cfprefs gen go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&sb, "package %s\n\n", g.opts.Package)
+
+	sb.WriteString("import (\n")
+	if g.imports["time"] {
+		sb.WriteString("\t\"time\"\n\n")
+	}
+	sb.WriteString("\t\"github.com/jheddings/go-cfprefs\"\n)\n\n")
+
+	fmt.Fprintf(&sb, "// %sAppID is the application ID of the preferences.\n", typ)
+	fmt.Fprintf(&sb, "const %sAppID = %s\n\n", typ, strconv.Quote(g.appID))
+
+	for _, st := range g.structs {
+		fmt.Fprintf(&sb, "// %s %s\n", st.name, st.doc)
+		fmt.Fprintf(&sb, "type %s struct {\n", st.name)
+		for _, field := range st.fields {
+			writeDoc(&sb, field.doc)
+			fmt.Fprintf(&sb, "%s %s `plist:%s`\n", field.name, field.goType, strconv.Quote(field.tag))
+		}
+		for _, key := range st.omitted {
+			fmt.Fprintf(&sb, "\n// %s is omitted, since it cannot be named in a struct tag.\n", strconv.Quote(key))
+		}
+		sb.WriteString("}\n\n")
+	}
+
+	fmt.Fprintf(&sb, "// Load%s reads the preferences of %s.\n", typ, g.appID)
+	fmt.Fprintf(&sb, "func Load%s() (*%s, error) {\n", typ, typ)
+	fmt.Fprintf(&sb, "var p %s\n", typ)
+	fmt.Fprintf(&sb, "if err := cfprefs.Load(%sAppID, &p); err != nil {\nreturn nil, err\n}\n", typ)
+	sb.WriteString("return &p, nil\n}\n\n")
+
+	fmt.Fprintf(&sb, "// Save writes the preferences that have changed to %s.\n", g.appID)
+	fmt.Fprintf(&sb, "func (p *%s) Save() error {\n", typ)
+	fmt.Fprintf(&sb, "return cfprefs.Save(%sAppID, p)\n}\n", typ)
+
+	if len(keys) > 0 {
+		sb.WriteString("\n// Typed keys for reading and writing single preferences.\nvar (\n")
+		for _, key := range keys {
+			writeDoc(&sb, key.doc)
+			fmt.Fprintf(&sb, "%s = cfprefs.Key[%s](%sAppID, %s)", key.name, key.goType, typ, strconv.Quote(key.key))
+			if key.def != "" {
+				fmt.Fprintf(&sb, ".Default(%s)", key.def)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(")\n")
+	}
+
+	return []byte(sb.String())
+}
+
+// writeDoc writes a comment, if any, on the lines before a declaration.
+func writeDoc(sb *strings.Builder, doc string) {
+	for line := range strings.Lines(doc) {
+		fmt.Fprintf(sb, "// %s\n", strings.TrimRight(line, "\n"))
+	}
+}
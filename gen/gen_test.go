@@ -0,0 +1,168 @@
+package gen
+
+import (
+	"go/parser"
+	"go/token"
+	"regexp"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs"
+	"github.com/jheddings/go-cfprefs/schema"
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestGo(t *testing.T) {
+	s, err := schema.Parse([]byte(`{
+		"type": "object",
+		"properties": {
+			"serverURL": {"type": "string", "description": "Address of the server.", "default": "https://example.com"},
+			"port": {"type": "integer", "format": "int32", "default": 8080},
+			"updated": {"type": "date"},
+			"my.key": {"type": "boolean"},
+			"servers": {
+				"type": "array",
+				"items": {"type": "object", "properties": {"host": {"type": "string"}}, "required": ["host"]}
+			},
+			"limits": {"type": "object", "additionalProperties": {"type": "integer", "format": "int16"}},
+			"extra": {"type": ["string", "integer"]}
+		},
+		"required": ["serverURL", "port"]
+	}`))
+	testutil.AssertNoError(t, err, "parse schema")
+
+	src, err := Go("com.example.app", s, Options{Package: "vendor", Type: "App"})
+	testutil.AssertNoError(t, err, "generate code")
+
+	expected := "// Code generated by cfprefs gen go; DO NOT EDIT.\n" +
+		"\n" +
+		"package vendor\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"time\"\n" +
+		"\n" +
+		"\t\"github.com/jheddings/go-cfprefs\"\n" +
+		")\n" +
+		"\n" +
+		"// AppAppID is the application ID of the preferences.\n" +
+		"const AppAppID = \"com.example.app\"\n" +
+		"\n" +
+		"// App describes the preferences of com.example.app.\n" +
+		"type App struct {\n" +
+		"\tExtra  any              `plist:\"extra,omitempty\"`\n" +
+		"\tLimits map[string]int16 `plist:\"limits,omitempty\"`\n" +
+		"\tMyKey  *bool            `plist:\"my.key\"`\n" +
+		"\tPort   int32            `plist:\"port\"`\n" +
+		"\t// Address of the server.\n" +
+		"\tServerURL string           `plist:\"serverURL\"`\n" +
+		"\tServers   []AppServersItem `plist:\"servers,omitempty\"`\n" +
+		"\tUpdated   *time.Time       `plist:\"updated\"`\n" +
+		"}\n" +
+		"\n" +
+		"// AppServersItem describes an item of servers.\n" +
+		"type AppServersItem struct {\n" +
+		"\tHost string `plist:\"host\"`\n" +
+		"}\n" +
+		"\n" +
+		"// LoadApp reads the preferences of com.example.app.\n" +
+		"func LoadApp() (*App, error) {\n" +
+		"\tvar p App\n" +
+		"\tif err := cfprefs.Load(AppAppID, &p); err != nil {\n" +
+		"\t\treturn nil, err\n" +
+		"\t}\n" +
+		"\treturn &p, nil\n" +
+		"}\n" +
+		"\n" +
+		"// Save writes the preferences that have changed to com.example.app.\n" +
+		"func (p *App) Save() error {\n" +
+		"\treturn cfprefs.Save(AppAppID, p)\n" +
+		"}\n" +
+		"\n" +
+		"// Typed keys for reading and writing single preferences.\n" +
+		"var (\n" +
+		"\tAppExtraKey = cfprefs.Key[any](AppAppID, \"extra\")\n" +
+		"\tAppPortKey  = cfprefs.Key[int32](AppAppID, \"port\").Default(8080)\n" +
+		"\t// Address of the server.\n" +
+		"\tAppServerURLKey = cfprefs.Key[string](AppAppID, \"serverURL\").Default(\"https://example.com\")\n" +
+		"\tAppUpdatedKey   = cfprefs.Key[time.Time](AppAppID, \"updated\")\n" +
+		")\n"
+
+	if string(src) != expected {
+		t.Errorf("unexpected code:\n%s", src)
+	}
+}
+
+func TestGoNameConflicts(t *testing.T) {
+	s := &schema.Schema{
+		Properties: map[string]*schema.Schema{
+			"server-host": {Type: schema.Types{schema.TypeString}},
+			"serverHost":  {Type: schema.Types{schema.TypeString}},
+			"load":        {Type: schema.Types{schema.TypeObject}, Properties: map[string]*schema.Schema{"a": {}}},
+			"save":        {Type: schema.Types{schema.TypeObject}, Properties: map[string]*schema.Schema{"save": {}}},
+		},
+	}
+
+	src, err := Go("com.example.app", s, Options{Type: "Prefs"})
+	testutil.AssertNoError(t, err, "generate code")
+
+	// the root struct has a Save method, but nested structs do not
+	decls := []string{
+		`ServerHost\s+\*string`, `ServerHost2\s+\*string`, `type PrefsLoad struct`, `PrefsServerHost2Key\s+=`,
+		`Save2\s+\*PrefsSave2\s`, `type PrefsSave2 struct {\s+Save\s+any`,
+	}
+
+	for _, decl := range decls {
+		if !regexp.MustCompile(decl).Match(src) {
+			t.Errorf("expected %q in generated code:\n%s", decl, src)
+		}
+	}
+}
+
+func TestGoFromDomain(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.gen"
+
+	defer cfprefs.Delete(appID, "config")
+	defer cfprefs.Delete(appID, "count")
+
+	testutil.AssertNoError(t, cfprefs.Set(appID, "count", int16(3)), "set count")
+	testutil.AssertNoError(t, cfprefs.Set(appID, "config", map[string]any{"name": "app"}), "set config")
+
+	src, err := GoFromDomain(appID, Options{})
+	testutil.AssertNoError(t, err, "generate code")
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "prefs.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+
+	for _, decl := range []string{`package prefs`, `Count\s+int16`, `type PreferencesConfig struct`, `PreferencesCountKey\s+=`} {
+		if !regexp.MustCompile(decl).Match(src) {
+			t.Errorf("expected %q in generated code:\n%s", decl, src)
+		}
+	}
+}
+
+func TestGoUntaggableKeys(t *testing.T) {
+	s := &schema.Schema{
+		Properties: map[string]*schema.Schema{
+			"a,b":     {Type: schema.Types{schema.TypeString}},
+			"quote`s": {Type: schema.Types{schema.TypeString}},
+			"name":    {Type: schema.Types{schema.TypeString}},
+		},
+	}
+
+	src, err := Go("com.example.app", s, Options{})
+	testutil.AssertNoError(t, err, "generate code")
+
+	if regexp.MustCompile(`AB\s|QuoteS\s|Key\[string\]\(PreferencesAppID, "a,b"\)`).Match(src) {
+		t.Errorf("expected keys that cannot be tagged to be omitted:\n%s", src)
+	}
+
+	for _, decl := range []string{`Name\s+\*string\s+` + "`" + `plist:"name"` + "`", `// "a,b" is omitted`, "// \"quote`s\" is omitted"} {
+		if !regexp.MustCompile(decl).Match(src) {
+			t.Errorf("expected %q in generated code:\n%s", decl, src)
+		}
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "prefs.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package gen
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in upper case when they form a word of a name, as
+// recommended for Go identifiers.
+var initialisms = map[string]bool{
+	"API": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "SSH": true, "TCP": true,
+	"TLS": true, "UDP": true, "UI": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// exportedName converts a preference key to an exported Go identifier, such
+// as "server-host" or "serverHost" to "ServerHost".
+func exportedName(key string) string {
+	var sb strings.Builder
+
+	for _, word := range splitWords(key) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+
+	name := sb.String()
+	switch {
+	case name == "":
+		return "Field"
+	case !unicode.IsLetter([]rune(name)[0]):
+		return "X" + name
+	}
+	return name
+}
+
+// splitWords splits a key into words at separators and at changes from lower
+// to upper case.
+func splitWords(key string) []string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	runes := []rune(key)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			// start a word at "serverHost" and at the "S" of "HTTPServer"
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+
+		word = append(word, r)
+	}
+	flush()
+
+	return words
+}
+
+// uniqueNames assigns distinct names within a scope, adding a number to
+// names that are already taken.
+type uniqueNames map[string]bool
+
+// add returns name, or name with the first free number appended.
+func (u uniqueNames) add(name string) string {
+	unique := name
+	for n := 2; u[unique]; n++ {
+		unique = name + strconv.Itoa(n)
+	}
+	u[unique] = true
+	return unique
+}
@@ -0,0 +1,35 @@
+package gen
+
+import "testing"
+
+func TestExportedName(t *testing.T) {
+	testCases := map[string]string{
+		"name":          "Name",
+		"serverHost":    "ServerHost",
+		"server-host":   "ServerHost",
+		"server_host":   "ServerHost",
+		"HTTPServer":    "HTTPServer",
+		"serverUrl":     "ServerURL",
+		"user id":       "UserID",
+		"NSWindow Size": "NSWindowSize",
+		"2fa":           "X2fa",
+		"":              "Field",
+		"---":           "Field",
+	}
+
+	for key, expected := range testCases {
+		if actual := exportedName(key); actual != expected {
+			t.Errorf("exportedName(%q): expected %q, got %q", key, expected, actual)
+		}
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	names := make(uniqueNames)
+
+	for _, expected := range []string{"Name", "Name2", "Name3"} {
+		if actual := names.add("Name"); actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
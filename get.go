@@ -1,15 +1,29 @@
 package cfprefs
 
 import (
+	"context"
+	"errors"
+	"strconv"
 	"time"
 
-	"github.com/go-openapi/jsonpointer"
 	"github.com/jheddings/go-cfprefs/internal"
 )
 
 // GetKeys retrieves all keys for the given appID.
 // Returns an error if the appID is not found.
 func GetKeys(appID string) ([]string, error) {
+	return GetKeysContext(context.Background(), appID)
+}
+
+// GetKeysContext retrieves all keys for the given appID, returning the
+// context error if ctx is done before the keys are read.
+func GetKeysContext(ctx context.Context, appID string) (_ []string, err error) {
+	defer wrapError(&err, "keys", appID, "")
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return internal.GetKeys(appID)
 }
 
@@ -28,109 +42,194 @@ func GetKeys(appID string) ([]string, error) {
 //
 // Returns the value at the specified path or an error if not found.
 func Get(appID, keypath string) (any, error) {
+	return GetContext(context.Background(), appID, keypath)
+}
+
+// GetContext retrieves a preference value like Get, honoring cancellation of
+// ctx before reading the value and while converting it.
+//
+// Returns the context error if ctx is done before the value is read.
+func GetContext(ctx context.Context, appID, keypath string) (_ any, err error) {
+	defer wrapError(&err, "get", appID, keypath)
+
 	kp, err := parseKeypath(keypath)
 	if err != nil {
 		return nil, NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
 	}
 
-	val, err := internal.Get(appID, kp.Key)
-	if err != nil {
-		return nil, NewKeyNotFoundError(appID, kp.Key).Wrap(err)
-	}
+	return getPath(ctx, appID, kp)
+}
 
-	// if there is no pointer, just return the value
-	if kp.IsRoot() {
-		return val, nil
+// GetPath retrieves a preference value for the given KeyPath and application ID.
+//
+// Example usage:
+//
+//	value, err := GetPath("com.example.app", Path("config", "servers", 0, "host"))
+//
+// Returns the value at the specified path or an error if not found.
+func GetPath(appID string, kp KeyPath) (_ any, err error) {
+	defer wrapError(&err, "get", appID, kp.String())
+
+	return getPath(context.Background(), appID, kp)
+}
+
+// getPath retrieves the value for a KeyPath, honoring cancellation of ctx.
+func getPath(ctx context.Context, appID string, kp KeyPath) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	ptr, err := jsonpointer.New(kp.Path)
+	val, err := internal.GetContext(ctx, appID, kp.Key())
 	if err != nil {
-		return nil, NewKeyPathError().Wrap(err).WithMsgF("invalid pointer: %s", kp.Path)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		// only a failed lookup means the key is missing
+		if errors.Is(err, internal.ErrCFLookup) {
+			return nil, NewKeyNotFoundError(appID, kp.Key()).Wrap(err)
+		}
+		return nil, NewInternalError().Wrap(err).WithMsgF("failed to get: %s", kp.Key())
 	}
 
-	result, _, err := ptr.Get(val)
+	result, err := getValueAtPath(val, kp.tokens)
 	if err != nil {
-		return nil, NewKeyNotFoundError(appID, kp.String()).Wrap(err)
+		return nil, resolveError(appID, kp, err)
 	}
 
 	return result, nil
 }
 
-// get retrieves a preference value for the given key and application ID and
-// converts it to the desired type.
-//
-// The keypath can be a simple name or include a JSON Pointer path (e.g.,
-// "config/server/port") to access nested values within the preference.
-//
-// Example usage:
-//
-//	// Get a simple value
-//	value, err := get[string]("com.example.app", "username")
-//
-//	// Get a nested value
-//	value, err := get[int64]("com.example.app", "config/server/port")
-//
-// Returns an error if the key doesn't exist or if the value is not of the given type.
-func get[T any](appID, keypath string) (T, error) {
-	var zero T
+// readRoot reads the value of a top-level key, reporting whether it exists.
+// Returns the context error if ctx is done before or while reading.
+func readRoot(ctx context.Context, appID, key string) (any, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
 
-	value, err := Get(appID, keypath)
+	exists, err := internal.Exists(appID, key)
 	if err != nil {
-		return zero, err
+		return nil, false, NewInternalError().Wrap(err).WithMsgF("failed to check: %s", key)
+	}
+
+	if !exists {
+		return nil, false, nil
 	}
 
-	typedValue, ok := value.(T)
-	if !ok {
-		return zero, NewTypeMismatchError(zero, value).WithKey(appID, keypath)
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
 	}
 
-	return typedValue, nil
+	value, err := internal.GetContext(ctx, appID, key)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, false, ctxErr
+		}
+		return nil, false, NewInternalError().Wrap(err).WithMsgF("failed to get: %s", key)
+	}
+
+	return value, true, nil
+}
+
+// getValueAtPath resolves the decoded pointer tokens against the given value.
+// It does no I/O and visits only one node per token, so it does not check a
+// context.
+func getValueAtPath(root any, tokens []string) (any, error) {
+	node := root
+
+	for i, token := range tokens {
+		switch container := node.(type) {
+		case map[string]any:
+			next, ok := container[token]
+			if !ok {
+				return nil, NewKeyPathError().AtToken(tokens, i).notFound().WithMsg("key not found")
+			}
+			node = next
+
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, NewKeyPathError().AtToken(tokens, i).WithMsg("invalid array index")
+			}
+			if index < 0 || index >= len(container) {
+				return nil, NewKeyPathError().AtToken(tokens, i).notFound().
+					WithMsgF("array index out of bounds: %d (array length: %d)", index, len(container))
+			}
+			node = container[index]
+
+		default:
+			expected := DictionaryKind
+			if _, err := strconv.Atoi(token); err == nil {
+				expected = ArrayKind
+			}
+			return nil, NewKeyPathError().AtToken(tokens, i).WithKinds(kindOf(node), expected).
+				WithMsg("cannot traverse through non-container value")
+		}
+	}
+
+	return node, nil
+}
+
+// resolveError returns the error for a keypath that could not be resolved
+// within its root value. A value that does not exist is reported as a
+// KeyNotFoundErr, while a path that runs through a value that is not a
+// container, or uses an invalid index, is reported as a KeyPathErr.
+func resolveError(appID string, kp KeyPath, err error) error {
+	var kpErr *KeyPathErr
+	if errors.As(err, &kpErr) && kpErr.missing {
+		// keep the segment details, but not the KeyPathErr itself, so the
+		// error does not also match ErrInvalidKeyPath
+		return NewKeyNotFoundError(appID, kp.String()).WithMsg(kpErr.detail())
+	}
+	return err
 }
 
 // GetStr retrieves a string preference value for the given key and application ID.
 // Returns an error if the key doesn't exist or if the value is not a string.
 func GetStr(appID, keypath string) (string, error) {
-	return get[string](appID, keypath)
+	return GetAs[string](appID, keypath)
 }
 
 // GetBool retrieves a boolean preference value for the given key and application ID.
 // Returns an error if the key doesn't exist or if the value is not a boolean.
 func GetBool(appID, keypath string) (bool, error) {
-	return get[bool](appID, keypath)
+	return GetAs[bool](appID, keypath)
 }
 
 // GetInt retrieves an integer preference value for the given key and application ID.
+// Numbers of any width, and reals with an integral value, are converted.
 // Returns an error if the key doesn't exist or if the value is not an integer.
 func GetInt(appID, keypath string) (int64, error) {
-	return get[int64](appID, keypath)
+	return GetAs[int64](appID, keypath)
 }
 
 // GetFloat retrieves a float preference value for the given key and application ID.
-// Returns an error if the key doesn't exist or if the value is not a float.
+// Integers are converted if they can be represented exactly.
+// Returns an error if the key doesn't exist or if the value is not a number.
 func GetFloat(appID, keypath string) (float64, error) {
-	return get[float64](appID, keypath)
+	return GetAs[float64](appID, keypath)
 }
 
 // GetDate retrieves a time.Time preference value for the given key and application ID.
 // Returns an error if the key doesn't exist or if the value is not a time.Time.
 func GetDate(appID, keypath string) (time.Time, error) {
-	return get[time.Time](appID, keypath)
+	return GetAs[time.Time](appID, keypath)
 }
 
 // GetData retrieves a []byte preference value for the given key and application ID.
 // Returns an error if the key doesn't exist or if the value is not a []byte.
 func GetData(appID, keypath string) ([]byte, error) {
-	return get[[]byte](appID, keypath)
+	return GetAs[[]byte](appID, keypath)
 }
 
 // GetSlice retrieves a []any preference value for the given key and application ID.
 // Returns an error if the key doesn't exist or if the value is not a []any.
 func GetSlice(appID, keypath string) ([]any, error) {
-	return get[[]any](appID, keypath)
+	return GetAs[[]any](appID, keypath)
 }
 
 // GetMap retrieves a map[string]any preference value for the given key and application ID.
 // Returns an error if the key doesn't exist or if the value is not a map[string]any.
 func GetMap(appID, keypath string) (map[string]any, error) {
-	return get[map[string]any](appID, keypath)
+	return GetAs[map[string]any](appID, keypath)
 }
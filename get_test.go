@@ -1,6 +1,8 @@
 package cfprefs
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -285,13 +287,25 @@ func TestGetQueryErrors(t *testing.T) {
 	appID := "com.jheddings.cfprefs.testing"
 
 	t.Run("Non-existent field", func(t *testing.T) {
-		_, err := Get(appID, "userData/nonexistent")
+		cleanup := setupTest(t, appID, "query-errors", map[string]any{"a": int64(1)})
+		defer cleanup()
+
+		_, err := Get(appID, "query-errors/b/c")
 		testutil.AssertError(t, err, "non-existent field should return error")
+
+		// a missing value is not a broken path
+		if !errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrInvalidKeyPath) {
+			t.Fatalf("expected only ErrKeyNotFound, got %v", err)
+		}
 	})
 
 	t.Run("Non-existent root key", func(t *testing.T) {
 		_, err := Get(appID, "nonexistent/name")
 		testutil.AssertError(t, err, "non-existent root key should return error")
+
+		if !errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrInvalidKeyPath) {
+			t.Fatalf("expected only ErrKeyNotFound, got %v", err)
+		}
 	})
 
 	t.Run("Invalid JSON Pointer", func(t *testing.T) {
@@ -327,3 +341,29 @@ func TestGetZ(t *testing.T) {
 	cleanup := setupTest(t, appID, "z-user-data", testData)
 	defer cleanup()
 }
+
+func TestGetContext(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "ctx-get-test", map[string]any{"name": "value"})
+	defer cleanup()
+
+	value, err := GetContext(context.Background(), appID, "ctx-get-test/name")
+	testutil.AssertNoError(t, err, "get with context")
+	if value != "value" {
+		t.Fatalf("expected 'value', got %v", value)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = GetContext(ctx, appID, "ctx-get-test/name")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	_, err = GetKeysContext(ctx, appID)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
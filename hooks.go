@@ -0,0 +1,209 @@
+package cfprefs
+
+import (
+	"context"
+)
+
+// WriteOp describes a single write of a value to a domain. Set and Delete
+// and their variants write one value at a time. Save, SetMultiple and
+// transactions write several values together, with an operation for each
+// changed top-level key, or for each keypath staged in a transaction.
+type WriteOp struct {
+	AppID   string
+	KeyPath KeyPath
+
+	// Old is the value at the keypath before the write, or nil if there was
+	// no value.
+	Old any
+
+	// New is the value being written, after conversion for storage, or nil if
+	// the value is being deleted.
+	New any
+}
+
+// IsDelete reports whether the operation deletes the value at its keypath.
+func (op WriteOp) IsDelete() bool {
+	return op.New == nil
+}
+
+// WriteFunc performs a write operation.
+type WriteFunc func(ctx context.Context, op WriteOp) error
+
+// WriteMiddleware wraps the function that performs a write operation, to
+// observe or veto it. It may inspect the operation, reject it by returning an
+// error without calling next, or act on the result of next. Middleware cannot
+// change the value that is written: next always performs the requested write,
+// and changes to the operation passed to next are only seen by the middleware
+// and hooks that follow. Returning without calling next or an error rejects
+// the write, and next must be called at most once. For writes performed
+// together, next also runs the chains of the operations that follow, so it
+// returns the result of the whole batch.
+type WriteMiddleware func(next WriteFunc) WriteFunc
+
+var (
+	beforeWriteHooks registry[func(ctx context.Context, op WriteOp) error]
+	afterWriteHooks  registry[func(ctx context.Context, op WriteOp)]
+	writeMiddleware  registry[WriteMiddleware]
+)
+
+// OnBeforeWrite adds a hook that runs before each value is written to or
+// deleted from the given appID, returning a function that removes it. Hooks
+// run in the order they were registered, after any validators, and the first
+// error stops the write and is returned to the caller.
+//
+// Example usage:
+//
+//	remove := cfprefs.OnBeforeWrite("com.example.app", func(ctx context.Context, op cfprefs.WriteOp) error {
+//		if op.IsDelete() && op.KeyPath.Key() == "license" {
+//			return errors.New("license cannot be deleted")
+//		}
+//		return nil
+//	})
+//	defer remove()
+func OnBeforeWrite(appID string, fn func(ctx context.Context, op WriteOp) error) (remove func()) {
+	if fn == nil {
+		panic("cfprefs: OnBeforeWrite with nil function")
+	}
+
+	return beforeWriteHooks.add(appID, fn)
+}
+
+// OnAfterWrite adds a hook that runs after each value is successfully written
+// to or deleted from the given appID, returning a function that removes it.
+// Hooks run in the order they were registered.
+//
+// Example usage:
+//
+//	remove := cfprefs.OnAfterWrite("com.example.app", func(ctx context.Context, op cfprefs.WriteOp) {
+//		log.Printf("%s: %v -> %v", op.KeyPath, op.Old, op.New)
+//	})
+//	defer remove()
+func OnAfterWrite(appID string, fn func(ctx context.Context, op WriteOp)) (remove func()) {
+	if fn == nil {
+		panic("cfprefs: OnAfterWrite with nil function")
+	}
+
+	return afterWriteHooks.add(appID, fn)
+}
+
+// UseWriteMiddleware adds middleware around each write to the given appID,
+// returning a function that removes it. The first middleware registered is
+// the outermost, and all middleware runs before the OnBeforeWrite hooks.
+//
+// Example usage:
+//
+//	remove := cfprefs.UseWriteMiddleware("com.example.app", func(next cfprefs.WriteFunc) cfprefs.WriteFunc {
+//		return func(ctx context.Context, op cfprefs.WriteOp) error {
+//			err := next(ctx, op)
+//			audit.Record(op.KeyPath.String(), op.Old, op.New, err)
+//			return err
+//		}
+//	})
+//	defer remove()
+func UseWriteMiddleware(appID string, mw WriteMiddleware) (remove func()) {
+	if mw == nil {
+		panic("cfprefs: UseWriteMiddleware with nil function")
+	}
+
+	return writeMiddleware.add(appID, mw)
+}
+
+// hasWriteHooks reports whether any hooks or middleware are registered for
+// the given appID.
+func hasWriteHooks(appID string) bool {
+	return len(beforeWriteHooks.get(appID)) > 0 ||
+		len(afterWriteHooks.get(appID)) > 0 ||
+		len(writeMiddleware.get(appID)) > 0
+}
+
+// runWrite passes a write operation through the middleware and hooks
+// registered for its domain, calling apply to perform it. The root value of
+// the keypath is only read, to find the old value, if there are any.
+func runWrite(ctx context.Context, op WriteOp, read func() (any, bool, error), apply func() error) error {
+	if !hasWriteHooks(op.AppID) {
+		return apply()
+	}
+
+	root, exists, err := read()
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		op.Old = oldValue(root, op.KeyPath)
+	}
+
+	return runWrites(ctx, op.AppID, []WriteOp{op}, apply)
+}
+
+// runWrites passes write operations that are performed together through the
+// middleware and hooks registered for their domain, calling apply once to
+// perform all of them.
+//
+// The chain for each operation calls the chain for the next one in place of
+// the write, and the last calls apply, so the middleware of every operation
+// sees the result of the whole batch. If any operation is rejected, apply is
+// not called. Otherwise the after-write hooks run for each operation, in
+// order, once all of them have been written.
+func runWrites(ctx context.Context, appID string, ops []WriteOp, apply func() error) error {
+	before := beforeWriteHooks.get(appID)
+	after := afterWriteHooks.get(appID)
+	middleware := writeMiddleware.get(appID)
+
+	if len(ops) == 0 || len(before) == 0 && len(after) == 0 && len(middleware) == 0 {
+		return apply()
+	}
+
+	// the operations as passed to the end of each chain
+	written := make([]WriteOp, len(ops))
+
+	var chainFor func(i int) WriteFunc
+	chainFor = func(i int) WriteFunc {
+		next := func(ctx context.Context, op WriteOp) error {
+			for _, fn := range before {
+				if err := fn(ctx, op); err != nil {
+					return err
+				}
+			}
+			written[i] = op
+
+			if i+1 < len(ops) {
+				return chainFor(i+1)(ctx, ops[i+1])
+			}
+
+			if err := apply(); err != nil {
+				return err
+			}
+
+			for _, op := range written {
+				for _, fn := range after {
+					fn(ctx, op)
+				}
+			}
+
+			return nil
+		}
+
+		chain := WriteFunc(next)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			chain = middleware[i](chain)
+		}
+		return chain
+	}
+
+	return chainFor(0)(ctx, ops[0])
+}
+
+// oldValue returns the value at a keypath within its root value, or nil if
+// there is none.
+func oldValue(root any, kp KeyPath) any {
+	if kp.IsRoot() {
+		return root
+	}
+
+	value, err := getValueAtPath(root, kp.tokens)
+	if err != nil {
+		return nil
+	}
+	return value
+}
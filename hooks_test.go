@@ -0,0 +1,355 @@
+package cfprefs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+// recordWrites registers an after-write hook that records every operation.
+func recordWrites(t *testing.T, appID string) *[]WriteOp {
+	t.Helper()
+
+	var ops []WriteOp
+	remove := OnAfterWrite(appID, func(ctx context.Context, op WriteOp) {
+		ops = append(ops, op)
+	})
+	t.Cleanup(remove)
+
+	return &ops
+}
+
+func TestWriteHooks(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	t.Run("Set and delete", func(t *testing.T) {
+		defer Delete(appID, "hooks-test")
+		ops := recordWrites(t, appID)
+
+		testutil.AssertNoError(t, Set(appID, "hooks-test", map[string]any{"port": 80}), "set root")
+		testutil.AssertNoError(t, Set(appID, "hooks-test/port", 8080), "set nested")
+		testutil.AssertNoError(t, Delete(appID, "hooks-test/port"), "delete nested")
+		testutil.AssertNoError(t, Set(appID, "hooks-test", nil), "delete root")
+
+		if len(*ops) != 4 {
+			t.Fatalf("expected 4 operations, got %d: %+v", len(*ops), *ops)
+		}
+
+		first := (*ops)[0]
+		if first.AppID != appID || first.KeyPath.String() != "hooks-test" || first.Old != nil || first.IsDelete() {
+			t.Fatalf("unexpected operation for new root: %+v", first)
+		}
+
+		nested := (*ops)[1]
+		if nested.KeyPath.String() != "hooks-test/port" || !valuesEqual(nested.Old, 80) || !valuesEqual(nested.New, 8080) {
+			t.Fatalf("unexpected operation for nested value: %+v", nested)
+		}
+
+		deleted := (*ops)[2]
+		if !deleted.IsDelete() || !valuesEqual(deleted.Old, 8080) {
+			t.Fatalf("unexpected operation for nested delete: %+v", deleted)
+		}
+
+		// a nil value deletes, so it is reported as a delete
+		root := (*ops)[3]
+		if !root.IsDelete() || !reflect.DeepEqual(root.Old, map[string]any{}) {
+			t.Fatalf("unexpected operation for root delete: %+v", root)
+		}
+	})
+
+	t.Run("Before write rejects", func(t *testing.T) {
+		errLocked := errors.New("locked")
+		ops := recordWrites(t, appID)
+
+		remove := OnBeforeWrite(appID, func(ctx context.Context, op WriteOp) error {
+			if op.KeyPath.Key() == "hooks-locked" {
+				return errLocked
+			}
+			return nil
+		})
+		defer remove()
+
+		err := Set(appID, "hooks-locked/value", "value")
+		if !errors.Is(err, errLocked) {
+			t.Fatalf("expected hook error, got %v", err)
+		}
+
+		var perr *Error
+		if !errors.As(err, &perr) || perr.Op != "set" || perr.KeyPath != "hooks-locked/value" {
+			t.Fatalf("expected a set error, got %v", err)
+		}
+
+		assertKeyExists(t, appID, "hooks-locked", false)
+
+		if len(*ops) != 0 {
+			t.Fatalf("expected no after-write hooks for a rejected write, got %+v", *ops)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		defer Delete(appID, "hooks-test")
+
+		called := 0
+		remove := OnBeforeWrite(appID, func(ctx context.Context, op WriteOp) error {
+			called++
+			return nil
+		})
+
+		testutil.AssertNoError(t, Set(appID, "hooks-test", 1), "set with hook")
+		remove()
+		remove()
+		testutil.AssertNoError(t, Set(appID, "hooks-test", 2), "set without hook")
+
+		if called != 1 {
+			t.Fatalf("expected hook to run once, ran %d times", called)
+		}
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		defer Delete(appID, "hooks-test")
+		ops := recordWrites(t, appID)
+
+		value, err := ValueOf(map[string]any{"level": int16(3)})
+		testutil.AssertNoError(t, err, "create value")
+		testutil.AssertNoError(t, SetValue(appID, "hooks-test", value), "set value")
+
+		if len(*ops) != 1 || !reflect.DeepEqual((*ops)[0].New, map[string]any{"level": int16(3)}) {
+			t.Fatalf("unexpected operations: %+v", *ops)
+		}
+	})
+
+	t.Run("Other domain", func(t *testing.T) {
+		ops := recordWrites(t, "com.jheddings.cfprefs.other")
+
+		defer Delete(appID, "hooks-test")
+		testutil.AssertNoError(t, Set(appID, "hooks-test", 1), "set in another domain")
+
+		if len(*ops) != 0 {
+			t.Fatalf("expected no operations, got %+v", *ops)
+		}
+	})
+}
+
+func TestWriteMiddleware(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	var calls []string
+
+	trace := func(name string) WriteMiddleware {
+		return func(next WriteFunc) WriteFunc {
+			return func(ctx context.Context, op WriteOp) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, op)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	removeOuter := UseWriteMiddleware(appID, trace("outer"))
+	defer removeOuter()
+
+	removeInner := UseWriteMiddleware(appID, trace("inner"))
+	defer removeInner()
+
+	removeHook := OnBeforeWrite(appID, func(ctx context.Context, op WriteOp) error {
+		calls = append(calls, "hook")
+		return nil
+	})
+	defer removeHook()
+
+	t.Run("Order", func(t *testing.T) {
+		defer Delete(appID, "middleware-test")
+		calls = nil
+
+		testutil.AssertNoError(t, Set(appID, "middleware-test", "value"), "set value")
+
+		expected := []string{"outer before", "inner before", "hook", "inner after", "outer after"}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Fatalf("expected %v, got %v", expected, calls)
+		}
+	})
+
+	t.Run("Short circuit", func(t *testing.T) {
+		errDenied := errors.New("denied")
+
+		remove := UseWriteMiddleware(appID, func(next WriteFunc) WriteFunc {
+			return func(ctx context.Context, op WriteOp) error {
+				return errDenied
+			}
+		})
+		defer remove()
+
+		err := Set(appID, "middleware-test", "value")
+		if !errors.Is(err, errDenied) {
+			t.Fatalf("expected middleware error, got %v", err)
+		}
+
+		assertKeyExists(t, appID, "middleware-test", false)
+	})
+}
+
+func TestDomainWriteHooks(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	d := Open(appID)
+
+	defer d.Delete("hooks-domain-test")
+	testutil.AssertNoError(t, d.Set("hooks-domain-test", map[string]any{"count": 1}), "set initial value")
+
+	// hooks may read the domain they are called for
+	var seen any
+	remove := OnAfterWrite(appID, func(ctx context.Context, op WriteOp) {
+		seen, _ = d.Get("hooks-domain-test/count")
+	})
+	defer remove()
+
+	ops := recordWrites(t, appID)
+
+	testutil.AssertNoError(t, d.Set("hooks-domain-test/count", 2), "set nested value")
+
+	if len(*ops) != 1 || !valuesEqual((*ops)[0].Old, 1) || !valuesEqual((*ops)[0].New, 2) {
+		t.Fatalf("unexpected operations: %+v", *ops)
+	}
+
+	if !valuesEqual(seen, 2) {
+		t.Fatalf("expected hook to read the new value, got %v", seen)
+	}
+}
+
+func TestBatchWriteHooks(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	t.Run("Transaction", func(t *testing.T) {
+		defer Delete(appID, "hooks-tx")
+		testutil.AssertNoError(t, Set(appID, "hooks-tx", map[string]any{"port": 80}), "set initial value")
+
+		var before []string
+		removeBefore := OnBeforeWrite(appID, func(ctx context.Context, op WriteOp) error {
+			before = append(before, op.KeyPath.String())
+			return nil
+		})
+		defer removeBefore()
+
+		ops := recordWrites(t, appID)
+
+		tx := Begin(appID)
+		testutil.AssertNoError(t, tx.Set("hooks-tx/port", 8080), "stage port")
+		testutil.AssertNoError(t, tx.Set("hooks-tx/port", 9090), "stage port again")
+		testutil.AssertNoError(t, tx.Delete("hooks-tx/host"), "stage delete")
+
+		if len(before) != 0 {
+			t.Fatalf("expected hooks to wait for commit, got %v", before)
+		}
+
+		testutil.AssertNoError(t, tx.Commit(), "commit")
+
+		expected := []string{"hooks-tx/port", "hooks-tx/port", "hooks-tx/host"}
+		if !reflect.DeepEqual(before, expected) {
+			t.Fatalf("expected before-write hooks for %v, got %v", expected, before)
+		}
+
+		if len(*ops) != 3 {
+			t.Fatalf("expected 3 operations, got %+v", *ops)
+		}
+
+		// the old value includes the earlier staged change
+		second := (*ops)[1]
+		if !valuesEqual(second.Old, 8080) || !valuesEqual(second.New, 9090) {
+			t.Fatalf("unexpected operation: %+v", second)
+		}
+
+		if !(*ops)[2].IsDelete() {
+			t.Fatalf("expected a delete, got %+v", (*ops)[2])
+		}
+	})
+
+	t.Run("Rejected transaction", func(t *testing.T) {
+		errDenied := errors.New("denied")
+		ops := recordWrites(t, appID)
+
+		var results []error
+		removeMiddleware := UseWriteMiddleware(appID, func(next WriteFunc) WriteFunc {
+			return func(ctx context.Context, op WriteOp) error {
+				err := next(ctx, op)
+				results = append(results, err)
+				return err
+			}
+		})
+		defer removeMiddleware()
+
+		removeBefore := OnBeforeWrite(appID, func(ctx context.Context, op WriteOp) error {
+			if op.KeyPath.Key() == "hooks-denied" {
+				return errDenied
+			}
+			return nil
+		})
+		defer removeBefore()
+
+		err := SetMultiple(appID, map[string]any{
+			"hooks-allowed": "value",
+			"hooks-denied":  "value",
+		})
+		if !errors.Is(err, errDenied) {
+			t.Fatalf("expected hook error, got %v", err)
+		}
+
+		// nothing is written, and every operation sees the failure
+		assertKeyExists(t, appID, "hooks-allowed", false)
+		assertKeyExists(t, appID, "hooks-denied", false)
+
+		if len(*ops) != 0 {
+			t.Fatalf("expected no after-write hooks, got %+v", *ops)
+		}
+
+		if len(results) != 2 || !errors.Is(results[0], errDenied) || !errors.Is(results[1], errDenied) {
+			t.Fatalf("expected middleware to see the rejection, got %v", results)
+		}
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		defer Delete(appID, "hooks-save-name")
+		defer Delete(appID, "hooks-save-count")
+		testutil.AssertNoError(t, Set(appID, "hooks-save-count", 1), "set initial value")
+
+		type config struct {
+			Name  string `plist:"hooks-save-name"`
+			Count int    `plist:"hooks-save-count"`
+			Skip  *bool  `plist:"hooks-save-skip"`
+		}
+
+		ops := recordWrites(t, appID)
+
+		testutil.AssertNoError(t, Save(appID, config{Name: "app", Count: 2}), "save struct")
+
+		if len(*ops) != 2 {
+			t.Fatalf("expected an operation for each changed key, got %+v", *ops)
+		}
+
+		count, name := (*ops)[0], (*ops)[1]
+		if count.KeyPath.String() != "hooks-save-count" || !valuesEqual(count.Old, 1) || !valuesEqual(count.New, 2) {
+			t.Fatalf("unexpected operation: %+v", count)
+		}
+		if name.KeyPath.String() != "hooks-save-name" || name.Old != nil || name.New != "app" {
+			t.Fatalf("unexpected operation: %+v", name)
+		}
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		remove := OnBeforeWrite(appID, func(ctx context.Context, op WriteOp) error {
+			panic("hook failed")
+		})
+		defer remove()
+
+		defer func() {
+			if r := recover(); r != "hook failed" {
+				t.Fatalf("expected the hook panic, got %v", r)
+			}
+			assertKeyExists(t, appID, "hooks-panic", false)
+		}()
+
+		_ = SetMultiple(appID, map[string]any{"hooks-panic": 1})
+	})
+}
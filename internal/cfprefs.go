@@ -4,6 +4,8 @@ package internal
 
 // TODO: fail gracefully if not running on macOS
 
+import "context"
+
 /*
 #cgo LDFLAGS: -framework CoreFoundation
 #include <CoreFoundation/CoreFoundation.h>
@@ -16,6 +18,24 @@ import "C"
 
 // Get retrieves a preference value for the given key and appID.
 func Get(appID, key string) (any, error) {
+	return GetContext(context.Background(), appID, key)
+}
+
+// GetContext retrieves a preference value for the given key and appID,
+// stopping the conversion early if ctx is done.
+func GetContext(ctx context.Context, appID, key string) (any, error) {
+	return copyValue(ctx, appID, key, false)
+}
+
+// GetRaw retrieves a preference value for the given key and appID without
+// interpreting data values, so the result can be written back unchanged.
+func GetRaw(appID, key string) (any, error) {
+	return copyValue(context.Background(), appID, key, true)
+}
+
+// copyValue reads and converts a preference value, optionally leaving data
+// values as raw bytes.
+func copyValue(ctx context.Context, appID, key string, raw bool) (any, error) {
 	appIDRef, err := createCFStringRef(appID)
 	if err != nil {
 		return nil, CFRefError().Wrap(err).WithMsg("failed to create CFString for appID")
@@ -35,7 +55,7 @@ func Get(appID, key string) (any, error) {
 	}
 	defer C.CFRelease(value)
 
-	goValue, err := convertCFTypeToGo(value)
+	goValue, err := convertCFTypeToGo(ctx, value, raw)
 	if err != nil {
 		return nil, CFTypeError().Wrap(err).WithMsg("failed to convert preference value")
 	}
@@ -66,8 +86,56 @@ func GetKeys(appID string) ([]string, error) {
 	return keys, nil
 }
 
+// GetMultiple retrieves the preference values for the given keys and appID.
+// Keys that do not exist are omitted from the result.
+//
+// Unlike Get, values are only read from the current-user, any-host domain.
+func GetMultiple(appID string, keys []string) (map[string]any, error) {
+	appIDRef, err := createCFStringRef(appID)
+	if err != nil {
+		return nil, CFRefError().Wrap(err).WithMsg("failed to create CFString for appID")
+	}
+	defer C.CFRelease(C.CFTypeRef(appIDRef))
+
+	keyList := make([]any, len(keys))
+	for i, key := range keys {
+		keyList[i] = key
+	}
+
+	keysRef, err := convertSliceToCF(context.Background(), keyList)
+	if err != nil {
+		return nil, CFTypeError().Wrap(err).WithMsg("failed to convert keys")
+	}
+	defer safeCFRelease(keysRef)
+
+	// https://developer.apple.com/documentation/corefoundation/cfpreferencescopymultiple(_:_:_:_:)
+	dictRef := C.CFPreferencesCopyMultiple(
+		C.CFArrayRef(keysRef),
+		appIDRef,
+		C.kCFPreferencesCurrentUser,
+		C.kCFPreferencesAnyHost,
+	)
+	if dictRef == nilCFDictionary {
+		return nil, CFLookupError().WithMsgF("failed to copy values from app '%s'", appID)
+	}
+	defer C.CFRelease(C.CFTypeRef(dictRef))
+
+	values, err := convertCFDictionaryToGo(context.Background(), dictRef, false)
+	if err != nil {
+		return nil, CFTypeError().Wrap(err).WithMsg("failed to convert preference values")
+	}
+
+	return values, nil
+}
+
 // Set updates a preference value for the given key and appID.
 func Set(appID, key string, value any) error {
+	return SetContext(context.Background(), appID, key, value)
+}
+
+// SetContext updates a preference value for the given key and appID, stopping
+// the conversion early if ctx is done.
+func SetContext(ctx context.Context, appID, key string, value any) error {
 	appIDRef, err := createCFStringRef(appID)
 	if err != nil {
 		return CFRefError().Wrap(err).WithMsg("failed to create CFString for appID")
@@ -80,7 +148,7 @@ func Set(appID, key string, value any) error {
 	}
 	defer C.CFRelease(C.CFTypeRef(keyRef))
 
-	valueRef, err := convertGoToCFType(value)
+	valueRef, err := convertGoToCFType(ctx, value)
 	if err != nil {
 		return CFTypeError().Wrap(err).WithMsg("failed to convert value")
 	}
@@ -100,6 +168,50 @@ func Set(appID, key string, value any) error {
 	return nil
 }
 
+// SetMultiple updates and removes multiple preference values for the given
+// appID, synchronizing only once after all changes have been applied.
+func SetMultiple(appID string, values map[string]any, remove []string) error {
+	appIDRef, err := createCFStringRef(appID)
+	if err != nil {
+		return CFRefError().Wrap(err).WithMsg("failed to create CFString for appID")
+	}
+	defer C.CFRelease(C.CFTypeRef(appIDRef))
+
+	setRef, err := convertMapToCF(context.Background(), values)
+	if err != nil {
+		return CFTypeError().Wrap(err).WithMsg("failed to convert values")
+	}
+	defer safeCFRelease(setRef)
+
+	keys := make([]any, len(remove))
+	for i, key := range remove {
+		keys[i] = key
+	}
+
+	removeRef, err := convertSliceToCF(context.Background(), keys)
+	if err != nil {
+		return CFTypeError().Wrap(err).WithMsg("failed to convert keys to remove")
+	}
+	defer safeCFRelease(removeRef)
+
+	// https://developer.apple.com/documentation/corefoundation/cfpreferencessetmultiple(_:_:_:_:_:)
+	C.CFPreferencesSetMultiple(
+		C.CFDictionaryRef(setRef),
+		C.CFArrayRef(removeRef),
+		appIDRef,
+		C.kCFPreferencesCurrentUser,
+		C.kCFPreferencesAnyHost,
+	)
+
+	// https://developer.apple.com/documentation/corefoundation/cfpreferencesappsynchronize(_:)
+	success := C.CFPreferencesAppSynchronize(appIDRef)
+	if success == 0 {
+		return CFSyncError().WithMsg("failed to synchronize preferences")
+	}
+
+	return nil
+}
+
 // Delete removes a preference value for the given key and appID.
 func Delete(appID, key string) error {
 	appIDRef, err := createCFStringRef(appID)
@@ -126,6 +238,24 @@ func Delete(appID, key string) error {
 	return nil
 }
 
+// Synchronize writes pending changes for the given appID and reloads any
+// changes made by other processes.
+func Synchronize(appID string) error {
+	appIDRef, err := createCFStringRef(appID)
+	if err != nil {
+		return CFRefError().Wrap(err).WithMsg("failed to create CFString for appID")
+	}
+	defer C.CFRelease(C.CFTypeRef(appIDRef))
+
+	// https://developer.apple.com/documentation/corefoundation/cfpreferencesappsynchronize(_:)
+	success := C.CFPreferencesAppSynchronize(appIDRef)
+	if success == 0 {
+		return CFSyncError().WithMsg("failed to synchronize preferences")
+	}
+
+	return nil
+}
+
 // Exists checks if a key exists for the given appID.
 func Exists(appID, key string) (bool, error) {
 	appIDRef, err := createCFStringRef(appID)
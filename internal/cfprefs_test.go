@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"math"
 	"math/rand"
 	"reflect"
@@ -26,7 +28,7 @@ func TestGetSetTypes(t *testing.T) {
 		{name: "uint8", val: uint8(rand.Uint32())},
 		{name: "uint16", val: uint16(rand.Uint32())},
 		{name: "uint32", val: rand.Uint32()},
-		{name: "uint64", val: rand.Uint64()},
+		{name: "uint64", val: rand.Uint64() >> 1},
 		{name: "float32", val: rand.Float32()},
 		{name: "float64", val: rand.Float64()},
 		{name: "bool-true", val: true},
@@ -86,6 +88,58 @@ func TestGetSetTypes(t *testing.T) {
 	}
 }
 
+func TestSetOutOfRange(t *testing.T) {
+	testCases := []struct {
+		name string
+		val  any
+	}{
+		{name: "uint64-max", val: uint64(math.MaxUint64)},
+		{name: "uint64-nested", val: map[string]any{"counter": uint64(math.MaxInt64) + 1}},
+		{name: "uint-max", val: uint(math.MaxUint)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Set("com.jheddings.cfprefs.testing", tc.name, tc.val)
+			if !errors.Is(err, ErrCFType) {
+				t.Fatalf("expected type error, got %v", err)
+			}
+
+			exists, err := Exists("com.jheddings.cfprefs.testing", tc.name)
+			testutil.AssertNoError(t, err, "check rejected key")
+			if exists {
+				t.Fatal("expected out of range value to not be written")
+			}
+		})
+	}
+}
+
+func TestSetNilElement(t *testing.T) {
+	testCases := []struct {
+		name string
+		val  any
+	}{
+		{name: "nil-slice-element", val: []any{"a", nil}},
+		{name: "nil-map-value", val: map[string]any{"a": nil}},
+		{name: "nil-nested", val: map[string]any{"items": []any{nil}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Set("com.jheddings.cfprefs.testing", tc.name, tc.val)
+			if !errors.Is(err, ErrCFType) {
+				t.Fatalf("expected type error, got %v", err)
+			}
+
+			exists, err := Exists("com.jheddings.cfprefs.testing", tc.name)
+			testutil.AssertNoError(t, err, "check rejected key")
+			if exists {
+				t.Fatal("expected value with nil element to not be written")
+			}
+		})
+	}
+}
+
 func TestMissingGet(t *testing.T) {
 	_, err := Get("com.jheddings.cfprefs.testing", "this-key-should-not-exist")
 	if err == nil {
@@ -148,3 +202,85 @@ func TestGetKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestSetMultiple(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	err := Set(appID, "multi-remove", "remove me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]any{
+		"multi-str": "hello",
+		"multi-int": int64(42),
+	}
+
+	err = SetMultiple(appID, values, []string{"multi-remove"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range values {
+		defer Delete(appID, key)
+
+		readVal, err := Get(appID, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !testutil.ValuesEqualApprox(expected, readVal) {
+			t.Fatalf("expected %v [%T], got %v [%T]", expected, expected, readVal, readVal)
+		}
+	}
+
+	exists, err := Exists(appID, "multi-remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("expected removed key to be deleted")
+	}
+}
+
+func TestGetMultiple(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	values := map[string]any{
+		"multi-get-str": "hello",
+		"multi-get-int": int64(42),
+	}
+
+	for key, value := range values {
+		if err := Set(appID, key, value); err != nil {
+			t.Fatal(err)
+		}
+		defer Delete(appID, key)
+	}
+
+	readVals, err := GetMultiple(appID, []string{"multi-get-str", "multi-get-int", "multi-get-missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(readVals, values) {
+		t.Fatalf("expected %v, got %v", values, readVals)
+	}
+}
+
+func TestSetContextCanceled(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := SetContext(ctx, appID, "ctx-canceled", []any{"first", "second"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	exists, err := Exists(appID, "ctx-canceled")
+	testutil.AssertNoError(t, err, "check canceled key")
+	if exists {
+		t.Fatal("expected canceled value to not be written")
+	}
+}
@@ -20,6 +20,7 @@ import "C"
 var nilCFType = C.CFTypeRef(unsafe.Pointer(nil))
 var nilCFString = C.CFStringRef(unsafe.Pointer(nil))
 var nilCFArray = C.CFArrayRef(unsafe.Pointer(nil))
+var nilCFDictionary = C.CFDictionaryRef(unsafe.Pointer(nil))
 
 // CFAbsoluteTimeIntervalSince1970 is the offset between CoreFoundation's epoch
 // (Jan 1, 2001 00:00:00 GMT) and Unix epoch (Jan 1, 1970 00:00:00 GMT).
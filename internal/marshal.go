@@ -3,6 +3,8 @@ package internal
 // This file contains functions to convert Go types to CoreFoundation types.
 
 import (
+	"context"
+	"math"
 	"time"
 	"unsafe"
 )
@@ -82,8 +84,9 @@ CFDictionaryRef createCFDictionary(const void** keys, const void** values, CFInd
 */
 import "C"
 
-// convertGoToCFType converts a native Go type to a CFTypeRef
-func convertGoToCFType(value any) (C.CFTypeRef, error) {
+// convertGoToCFType converts a native Go type to a CFTypeRef, stopping early
+// if ctx is done
+func convertGoToCFType(ctx context.Context, value any) (C.CFTypeRef, error) {
 	if value == nil {
 		return nilCFType, nil
 	}
@@ -113,7 +116,7 @@ func convertGoToCFType(value any) (C.CFTypeRef, error) {
 
 	case uint:
 		// uint is platform-dependent, use int64 for safety
-		return convertInt64ToCF(int64(v)), nil
+		return convertUint64ToCF(uint64(v))
 
 	case uint8:
 		// Use int16 to safely represent full uint8 range (0-255)
@@ -128,8 +131,8 @@ func convertGoToCFType(value any) (C.CFTypeRef, error) {
 		return convertInt64ToCF(int64(v)), nil
 
 	case uint64:
-		// Use int64 (may overflow for values > 2^63-1)
-		return convertInt64ToCF(int64(v)), nil
+		// Use int64, rejecting values > 2^63-1 rather than wrapping
+		return convertUint64ToCF(v)
 
 	case float32:
 		return convertFloat32ToCF(v), nil
@@ -144,10 +147,10 @@ func convertGoToCFType(value any) (C.CFTypeRef, error) {
 		return convertBytesToCF(v), nil
 
 	case []any:
-		return convertSliceToCF(v)
+		return convertSliceToCF(ctx, v)
 
 	case map[string]any:
-		return convertMapToCF(v)
+		return convertMapToCF(ctx, v)
 	}
 
 	return nilCFType, CFTypeError().WithMsgF("unsupported Go type: %T", value)
@@ -198,6 +201,15 @@ func convertInt64ToCF(value int64) C.CFTypeRef {
 	return C.CFTypeRef(numRef)
 }
 
+// convertUint64ToCF converts a Go uint64 to a CFNumberRef, returning an error
+// if the value does not fit in a signed 64-bit integer
+func convertUint64ToCF(value uint64) (C.CFTypeRef, error) {
+	if value > math.MaxInt64 {
+		return nilCFType, CFTypeError().WithMsgF("unsigned value out of range for CFNumber: %d", value)
+	}
+	return convertInt64ToCF(int64(value)), nil
+}
+
 // convertFloat32ToCF converts a Go float32 to a CFNumberRef
 func convertFloat32ToCF(value float32) C.CFTypeRef {
 	numRef := C.createCFNumberFloat32(C.float(value))
@@ -231,7 +243,7 @@ func convertBytesToCF(value []byte) C.CFTypeRef {
 }
 
 // convertSliceToCF converts a Go []any to a CFArrayRef
-func convertSliceToCF(value []any) (C.CFTypeRef, error) {
+func convertSliceToCF(ctx context.Context, value []any) (C.CFTypeRef, error) {
 	if len(value) == 0 {
 		arrRef := C.createCFArray(nil, 0)
 		return C.CFTypeRef(arrRef), nil
@@ -245,7 +257,16 @@ func convertSliceToCF(value []any) (C.CFTypeRef, error) {
 	}()
 
 	for i, v := range value {
-		cfValue, err := convertGoToCFType(v)
+		if err := ctx.Err(); err != nil {
+			return nilCFType, err
+		}
+
+		// CoreFoundation arrays cannot contain NULL
+		if v == nil {
+			return nilCFType, CFTypeError().WithMsgF("nil slice element %d", i)
+		}
+
+		cfValue, err := convertGoToCFType(ctx, v)
 		if err != nil {
 			return nilCFType, CFTypeError().Wrap(err).WithMsgF("failed to convert slice element %d", i)
 		}
@@ -257,7 +278,7 @@ func convertSliceToCF(value []any) (C.CFTypeRef, error) {
 }
 
 // convertMapToCF converts a Go map[string]any to a CFDictionaryRef
-func convertMapToCF(value map[string]any) (C.CFTypeRef, error) {
+func convertMapToCF(ctx context.Context, value map[string]any) (C.CFTypeRef, error) {
 	if len(value) == 0 {
 		dictRef := C.createCFDictionary(nil, nil, 0)
 		return C.CFTypeRef(dictRef), nil
@@ -276,13 +297,22 @@ func convertMapToCF(value map[string]any) (C.CFTypeRef, error) {
 	}()
 
 	for k, v := range value {
+		if err := ctx.Err(); err != nil {
+			return nilCFType, err
+		}
+
 		keyRef, err := convertStringToCF(k)
 		if err != nil {
 			return nilCFType, CFTypeError().Wrap(err).WithMsgF("failed to convert map key '%s'", k)
 		}
 		cfKeys = append(cfKeys, unsafe.Pointer(keyRef))
 
-		valueRef, err := convertGoToCFType(v)
+		// CoreFoundation dictionaries cannot contain NULL
+		if v == nil {
+			return nilCFType, CFTypeError().WithMsgF("nil value for key '%s'", k)
+		}
+
+		valueRef, err := convertGoToCFType(ctx, v)
 		if err != nil {
 			return nilCFType, CFTypeError().Wrap(err).WithMsgF("failed to convert value for key '%s'", k)
 		}
@@ -3,6 +3,7 @@ package internal
 // This file contains functions to convert CoreFoundation types to Go types.
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 	"unsafe"
@@ -116,8 +117,9 @@ CFPropertyListRef tryDeserializePlist(CFDataRef data) {
 */
 import "C"
 
-// converts a CFTypeRef to a native Go type
-func convertCFTypeToGo(cfValue C.CFTypeRef) (any, error) {
+// converts a CFTypeRef to a native Go type, stopping early if ctx is done; in
+// raw mode, data values are returned as bytes without being interpreted
+func convertCFTypeToGo(ctx context.Context, cfValue C.CFTypeRef, raw bool) (any, error) {
 	if cfValue == nilCFType {
 		return nil, nil
 	}
@@ -135,16 +137,19 @@ func convertCFTypeToGo(cfValue C.CFTypeRef) (any, error) {
 		return convertCFBooleanToGo(C.CFBooleanRef(cfValue)), nil
 
 	case C.CFArrayGetTypeID():
-		return convertCFArrayToGo(C.CFArrayRef(cfValue))
+		return convertCFArrayToGo(ctx, C.CFArrayRef(cfValue), raw)
 
 	case C.CFDictionaryGetTypeID():
-		return convertCFDictionaryToGo(C.CFDictionaryRef(cfValue))
+		return convertCFDictionaryToGo(ctx, C.CFDictionaryRef(cfValue), raw)
 
 	case C.CFDateGetTypeID():
 		return convertCFDateToGo(C.CFDateRef(cfValue)), nil
 
 	case C.CFDataGetTypeID():
-		return convertCFDataToGo(C.CFDataRef(cfValue)), nil
+		if raw {
+			return convertCFDataToBytes(C.CFDataRef(cfValue)), nil
+		}
+		return convertCFDataToGo(ctx, C.CFDataRef(cfValue)), nil
 	}
 
 	return nil, CFTypeError().WithMsgF("unsupported CFType: %v", typeID)
@@ -227,20 +232,28 @@ func convertCFDateToGo(dateRef C.CFDateRef) time.Time {
 	return time.Unix(seconds, nanoseconds)
 }
 
-// converts a complex CFDataRef to a Go value
-func convertCFDataToGo(dataRef C.CFDataRef) any {
+// converts a CFDataRef to a Go []byte
+func convertCFDataToBytes(dataRef C.CFDataRef) []byte {
 	length := int(C.getCFDataLength(dataRef))
 	if length == 0 {
 		return []byte{}
 	}
 
 	bytes := C.getCFDataBytes(dataRef)
-	data := C.GoBytes(unsafe.Pointer(bytes), C.int(length))
+	return C.GoBytes(unsafe.Pointer(bytes), C.int(length))
+}
+
+// converts a complex CFDataRef to a Go value
+func convertCFDataToGo(ctx context.Context, dataRef C.CFDataRef) any {
+	data := convertCFDataToBytes(dataRef)
+	if len(data) == 0 {
+		return data
+	}
 
 	// first, try to deserialize as property list
 	if plist := C.tryDeserializePlist(dataRef); plist != nilCFType {
 		defer C.CFRelease(C.CFTypeRef(plist))
-		if value, err := convertCFTypeToGo(C.CFTypeRef(plist)); err == nil {
+		if value, err := convertCFTypeToGo(ctx, C.CFTypeRef(plist), false); err == nil {
 			return value
 		}
 	}
@@ -256,13 +269,17 @@ func convertCFDataToGo(dataRef C.CFDataRef) any {
 }
 
 // converts a CFArrayRef to a Go slice
-func convertCFArrayToGo(arrRef C.CFArrayRef) ([]any, error) {
+func convertCFArrayToGo(ctx context.Context, arrRef C.CFArrayRef, raw bool) ([]any, error) {
 	count := int(C.getCFArrayCount(arrRef))
 	result := make([]any, count)
 
 	for i := range count {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		cfValue := C.getCFArrayValueAtIndex(arrRef, C.CFIndex(i))
-		value, err := convertCFTypeToGo(cfValue)
+		value, err := convertCFTypeToGo(ctx, cfValue, raw)
 		if err != nil {
 			return nil, CFTypeError().Wrap(err).WithMsgF("failed to convert array element %d", i)
 		}
@@ -290,7 +307,7 @@ func convertCFArrayToGoStr(arrRef C.CFArrayRef) ([]string, error) {
 }
 
 // converts a CFDictionaryRef to a Go map
-func convertCFDictionaryToGo(dictRef C.CFDictionaryRef) (map[string]any, error) {
+func convertCFDictionaryToGo(ctx context.Context, dictRef C.CFDictionaryRef, raw bool) (map[string]any, error) {
 	count := int(C.getCFDictionaryCount(dictRef))
 	if count == 0 {
 		return make(map[string]any), nil
@@ -301,6 +318,10 @@ func convertCFDictionaryToGo(dictRef C.CFDictionaryRef) (map[string]any, error)
 	result := make(map[string]any, count)
 
 	for i := range count {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		keyRef := C.CFStringRef(keys[i])
 		keyStr, err := convertCFStringToGo(keyRef)
 		if err != nil {
@@ -308,7 +329,7 @@ func convertCFDictionaryToGo(dictRef C.CFDictionaryRef) (map[string]any, error)
 		}
 
 		valueRef := C.getCFDictionaryValue(dictRef, keyRef)
-		value, err := convertCFTypeToGo(valueRef)
+		value, err := convertCFTypeToGo(ctx, valueRef, raw)
 		if err != nil {
 			return nil, CFTypeError().Wrap(err).WithMsgF("failed to convert dictionary value for key '%s'", keyStr)
 		}
@@ -0,0 +1,184 @@
+package cfprefs
+
+import (
+	"context"
+	"errors"
+)
+
+// TypedKey describes a preference of type T at a fixed keypath, with an
+// optional default value and validator. A TypedKey is immutable and safe for
+// concurrent use, so it is typically declared once as a package-level
+// variable.
+type TypedKey[T any] struct {
+	appID    string
+	keypath  string
+	def      T
+	hasDef   bool
+	validate func(T) error
+}
+
+// Key creates a descriptor for the preference of type T at the given keypath.
+//
+// Values are read with GetAs using the default coercion policy.
+//
+// Example usage:
+//
+//	var ServerPort = cfprefs.Key[int]("com.example.app", "config/server/port").
+//		Default(8080).
+//		Validate(func(port int) error {
+//			if port < 1 || port > 65535 {
+//				return errors.New("port out of range")
+//			}
+//			return nil
+//		})
+//
+//	port, err := ServerPort.Get()
+//	err = ServerPort.Set(9090)
+func Key[T any](appID, keypath string) *TypedKey[T] {
+	return &TypedKey[T]{appID: appID, keypath: keypath}
+}
+
+// Default returns a copy of the key that returns v when the preference does
+// not exist.
+func (k *TypedKey[T]) Default(v T) *TypedKey[T] {
+	key := *k
+	key.def = v
+	key.hasDef = true
+	return &key
+}
+
+// Validate returns a copy of the key that checks values with fn when they are
+// read or written. An error from fn is returned as a ValidationErr.
+func (k *TypedKey[T]) Validate(fn func(T) error) *TypedKey[T] {
+	key := *k
+	key.validate = fn
+	return &key
+}
+
+// AppID returns the application ID of the key.
+func (k *TypedKey[T]) AppID() string {
+	return k.appID
+}
+
+// KeyPath returns the keypath of the key.
+func (k *TypedKey[T]) KeyPath() string {
+	return k.keypath
+}
+
+// Get retrieves the value of the key, returning the default if the preference
+// does not exist. Returns an error if the stored value cannot be converted to
+// T or is rejected by the validator.
+func (k *TypedKey[T]) Get() (_ T, err error) {
+	defer wrapError(&err, "get", k.appID, k.keypath)
+
+	value, err := Get(k.appID, k.keypath)
+	return k.resolve(value, err)
+}
+
+// Set validates and writes the value of the key.
+func (k *TypedKey[T]) Set(v T) (err error) {
+	defer wrapError(&err, "set", k.appID, k.keypath)
+
+	if err := k.check(v); err != nil {
+		return err
+	}
+	return Set(k.appID, k.keypath, v)
+}
+
+// Delete removes the value of the key, so Get returns the default.
+func (k *TypedKey[T]) Delete() (err error) {
+	defer wrapError(&err, "delete", k.appID, k.keypath)
+
+	return Delete(k.appID, k.keypath)
+}
+
+// Watch returns a channel that receives the value of the key, first when
+// called and then each time the stored value changes. Values that cannot be
+// read, such as a missing key without a default or a value rejected by the
+// validator, are skipped. The channel is closed when ctx is done.
+func (k *TypedKey[T]) Watch(ctx context.Context) <-chan T {
+	ch := make(chan T, 1)
+
+	// start watching before reading, so no change is missed in between
+	changes := Watch(ctx, k.appID, k.keypath)
+
+	go func() {
+		defer close(ch)
+
+		send := func(raw any, err error) bool {
+			value, err := k.resolve(raw, err)
+			if err != nil {
+				return true
+			}
+			select {
+			case ch <- value:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		last, err := GetContext(ctx, k.appID, k.keypath)
+		if ctx.Err() != nil || !send(last, err) {
+			return
+		}
+
+		for change := range changes {
+			if change.Err != nil {
+				continue
+			}
+
+			// the first change may already be reflected in the value sent
+			if valuesEqual(change.New, last) && (change.New == nil) == (last == nil) {
+				continue
+			}
+			last = change.New
+
+			err = nil
+			if change.New == nil {
+				err = NewKeyNotFoundError(k.appID, k.keypath)
+			}
+
+			if !send(change.New, err) {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// resolve converts a stored value, or the error from reading it, to the value
+// of the key.
+func (k *TypedKey[T]) resolve(raw any, err error) (T, error) {
+	var zero T
+
+	if errors.Is(err, ErrKeyNotFound) && k.hasDef {
+		return k.def, nil
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	value, err := coerce[T](k.appID, k.keypath, raw, CoerceNumbers)
+	if err != nil {
+		return zero, err
+	}
+
+	if err := k.check(value); err != nil {
+		return zero, err
+	}
+
+	return value, nil
+}
+
+// check runs the validator, if any, on a value.
+func (k *TypedKey[T]) check(v T) error {
+	if k.validate == nil {
+		return nil
+	}
+	if err := k.validate(v); err != nil {
+		return NewValidationError(v).WithKey(k.appID, k.keypath).Wrap(err)
+	}
+	return nil
+}
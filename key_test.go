@@ -0,0 +1,125 @@
+package cfprefs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestKey(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	errInvalidPort := errors.New("invalid port")
+
+	port := Key[int](appID, "key-test/port").
+		Default(8080).
+		Validate(func(p int) error {
+			if p < 1 || p > 65535 {
+				return errInvalidPort
+			}
+			return nil
+		})
+
+	// missing values return the default
+	value, err := port.Get()
+	testutil.AssertNoError(t, err, "get default")
+	if value != 8080 {
+		t.Fatalf("expected default 8080, got %d", value)
+	}
+
+	err = port.Set(9090)
+	testutil.AssertNoError(t, err, "set port")
+	defer Delete(appID, "key-test")
+
+	value, err = port.Get()
+	testutil.AssertNoError(t, err, "get port")
+	if value != 9090 {
+		t.Fatalf("expected 9090, got %d", value)
+	}
+
+	// invalid values are not written
+	err = port.Set(70000)
+	if !errors.Is(err, ErrInvalidValue) || !errors.Is(err, errInvalidPort) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	value, err = port.Get()
+	testutil.AssertNoError(t, err, "get after invalid set")
+	if value != 9090 {
+		t.Fatalf("expected 9090, got %d", value)
+	}
+
+	// invalid values written elsewhere are rejected when read
+	err = Set(appID, "key-test/port", int32(-1))
+	testutil.AssertNoError(t, err, "set invalid port directly")
+
+	_, err = port.Get()
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	err = port.Delete()
+	testutil.AssertNoError(t, err, "delete port")
+
+	value, err = port.Get()
+	testutil.AssertNoError(t, err, "get after delete")
+	if value != 8080 {
+		t.Fatalf("expected default 8080, got %d", value)
+	}
+
+	// keys without a default return an error when missing
+	_, err = Key[string](appID, "key-test/missing").Get()
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected key not found, got %v", err)
+	}
+
+	// Default and Validate do not modify the original key
+	base := Key[int](appID, "key-test/port")
+	_ = base.Default(1)
+	if _, err := base.Get(); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected base key to have no default, got %v", err)
+	}
+}
+
+func TestKeyWatch(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	interval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	defer func() { watchInterval = interval }()
+
+	name := Key[string](appID, "key-watch-test").Default("initial")
+	defer Delete(appID, "key-watch-test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := name.Watch(ctx)
+
+	receive := func(expected string) {
+		t.Helper()
+		select {
+		case value := <-changes:
+			if value != expected {
+				t.Fatalf("expected %q, got %q", expected, value)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+
+	receive("initial")
+
+	testutil.AssertNoError(t, name.Set("updated"), "set name")
+	receive("updated")
+
+	testutil.AssertNoError(t, name.Delete(), "delete name")
+	receive("initial")
+
+	cancel()
+	for range changes {
+	}
+}
@@ -0,0 +1,488 @@
+package cfprefs
+
+import (
+	"encoding"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// prepareNode converts a node of a value into the plain types understood by
+// the preferences system, descending into containers. Reports whether the
+// returned node differs from the original.
+//
+// Typed slices, arrays, maps with string keys, pointers and structs are
+// converted using reflection. Types that implement encoding.TextMarshaler are
+// stored as strings. Nil pointers, slices and maps are treated as absent: they
+// are omitted from structs, and handled by the nil policy inside arrays and
+// maps. Types with a registered converter are converted before any of these
+// rules apply.
+func prepareNode(appID string, kp KeyPath, node any, policy writePolicy) (any, bool, error) {
+	if conv, ok := lookupConverter(reflect.TypeOf(node)); ok {
+		return encodeConverted(appID, kp, node, conv, policy)
+	}
+
+	switch v := node.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint8, uint16, uint32, float32, float64, time.Time, []byte:
+		return node, false, nil
+
+	case uint:
+		return prepareUint(appID, kp, v, uint64(v), policy.overflow)
+
+	case uint64:
+		return prepareUint(appID, kp, v, v, policy.overflow)
+
+	case Value:
+		return v.Interface(), true, nil
+
+	case []any:
+		leave, err := enterVisit(appID, kp, reflect.ValueOf(v), &policy)
+		if err != nil {
+			return nil, false, err
+		}
+		defer leave()
+
+		var result []any
+		for idx, elem := range v {
+			prepared, changed, err := prepareNode(appID, childPath(kp, idx), elem, policy)
+			if err != nil {
+				return nil, false, err
+			}
+
+			omit, err := omitNil(appID, childPath(kp, idx), prepared, policy)
+			if err != nil {
+				return nil, false, err
+			}
+
+			// copy the preceding elements on the first replaced or omitted element
+			if (changed || omit) && result == nil {
+				result = make([]any, idx, len(v))
+				copy(result, v[:idx])
+			}
+			if result != nil && !omit {
+				result = append(result, prepared)
+			}
+		}
+		if result == nil {
+			return v, false, nil
+		}
+		return result, true, nil
+
+	case map[string]any:
+		leave, err := enterVisit(appID, kp, reflect.ValueOf(v), &policy)
+		if err != nil {
+			return nil, false, err
+		}
+		defer leave()
+
+		var result map[string]any
+		for key, elem := range v {
+			prepared, changed, err := prepareNode(appID, childPath(kp, key), elem, policy)
+			if err != nil {
+				return nil, false, err
+			}
+
+			omit, err := omitNil(appID, childPath(kp, key), prepared, policy)
+			if err != nil {
+				return nil, false, err
+			}
+
+			// copy the map on the first replaced or omitted element
+			if (changed || omit) && result == nil {
+				result = maps.Clone(v)
+			}
+			if omit {
+				delete(result, key)
+			} else if result != nil {
+				result[key] = prepared
+			}
+		}
+		if result == nil {
+			return v, false, nil
+		}
+		return result, true, nil
+
+	case encoding.TextMarshaler:
+		return marshalText(appID, kp, v)
+	}
+
+	prepared, err := marshalReflect(appID, kp, reflect.ValueOf(node), policy)
+	return prepared, true, err
+}
+
+// visit identifies a pointer, map or slice by its address and type.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// enterVisit records a pointer, map or slice as being converted, so that a
+// value containing itself is reported rather than converted forever. Returns
+// a function that removes the record, or a TypeMismatchErr if the value is
+// already being converted.
+func enterVisit(appID string, kp KeyPath, rv reflect.Value, policy *writePolicy) (func(), error) {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return func() {}, nil
+		}
+	default:
+		return func() {}, nil
+	}
+
+	v := visit{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		v.len = rv.Len()
+	}
+
+	if policy.visiting == nil {
+		policy.visiting = make(map[visit]bool)
+	}
+	if policy.visiting[v] {
+		return nil, NewTypeMismatchError(nil, rv.Interface()).WithKey(appID, kp.String()).
+			Wrap(fmt.Errorf("encountered a cycle via %s", rv.Type()))
+	}
+
+	policy.visiting[v] = true
+	return func() { delete(policy.visiting, v) }, nil
+}
+
+// marshalReflect converts a value of any supported type using reflection.
+// Returns a TypeMismatchErr if a pointer, map or slice contains itself.
+func marshalReflect(appID string, kp KeyPath, rv reflect.Value, policy writePolicy) (any, error) {
+	leave, err := enterVisit(appID, kp, rv, &policy)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		prepared, _, err := prepareNode(appID, kp, rv.Elem().Interface(), policy)
+		return prepared, err
+
+	case reflect.String:
+		return rv.String(), nil
+
+	case reflect.Bool:
+		return rv.Bool(), nil
+
+	case reflect.Int, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Int8:
+		return int8(rv.Int()), nil
+	case reflect.Int16:
+		return int16(rv.Int()), nil
+	case reflect.Int32:
+		return int32(rv.Int()), nil
+
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		prepared, _, err := prepareUint(appID, kp, rv.Interface(), rv.Uint(), policy.overflow)
+		return prepared, err
+	case reflect.Uint8:
+		return uint8(rv.Uint()), nil
+	case reflect.Uint16:
+		return uint16(rv.Uint()), nil
+	case reflect.Uint32:
+		return uint32(rv.Uint()), nil
+
+	case reflect.Float32:
+		return float32(rv.Float()), nil
+	case reflect.Float64:
+		return rv.Float(), nil
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+		return marshalList(appID, kp, rv, policy)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return data, nil
+		}
+		return marshalList(appID, kp, rv, policy)
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, NewTypeMismatchError(nil, rv.Interface()).WithKey(appID, kp.String())
+		}
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return marshalMap(appID, kp, rv, policy)
+
+	case reflect.Struct:
+		return marshalStruct(appID, kp, rv, policy)
+	}
+
+	return nil, NewTypeMismatchError(nil, rv.Interface()).WithKey(appID, kp.String())
+}
+
+// marshalText converts a TextMarshaler to a string.
+func marshalText(appID string, kp KeyPath, v encoding.TextMarshaler) (any, bool, error) {
+	// a nil receiver is absent rather than an error
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if rv.IsNil() {
+			return nil, true, nil
+		}
+	}
+
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, false, NewTypeMismatchError("", v).WithKey(appID, kp.String()).Wrap(err)
+	}
+
+	return string(text), true, nil
+}
+
+// marshalList converts a slice or array to []any. Nil elements are handled
+// according to the nil policy.
+func marshalList(appID string, kp KeyPath, rv reflect.Value, policy writePolicy) (any, error) {
+	result := make([]any, 0, rv.Len())
+	for idx := range rv.Len() {
+		prepared, _, err := prepareNode(appID, childPath(kp, idx), rv.Index(idx).Interface(), policy)
+		if err != nil {
+			return nil, err
+		}
+
+		omit, err := omitNil(appID, childPath(kp, idx), prepared, policy)
+		if err != nil {
+			return nil, err
+		}
+		if !omit {
+			result = append(result, prepared)
+		}
+	}
+	return result, nil
+}
+
+// marshalMap converts a map with string keys to map[string]any. Nil values
+// are handled according to the nil policy.
+func marshalMap(appID string, kp KeyPath, rv reflect.Value, policy writePolicy) (any, error) {
+	result := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		prepared, _, err := prepareNode(appID, childPath(kp, key), iter.Value().Interface(), policy)
+		if err != nil {
+			return nil, err
+		}
+
+		omit, err := omitNil(appID, childPath(kp, key), prepared, policy)
+		if err != nil {
+			return nil, err
+		}
+		if !omit {
+			result[key] = prepared
+		}
+	}
+	return result, nil
+}
+
+// omitNil reports whether a prepared element of a container should be omitted
+// because it is nil, or returns a NilValueErr if the policy rejects it.
+func omitNil(appID string, kp KeyPath, prepared any, policy writePolicy) (bool, error) {
+	if prepared != nil {
+		return false, nil
+	}
+	if policy.nils == NilOmit {
+		return true, nil
+	}
+	return false, NewNilValueError().WithKey(appID, kp.String())
+}
+
+// marshalStruct converts a struct to map[string]any using its field tags.
+// Fields with absent values are always omitted.
+func marshalStruct(appID string, kp KeyPath, rv reflect.Value, policy writePolicy) (any, error) {
+	result := make(map[string]any)
+	for _, field := range structFields(rv.Type()) {
+		fv, err := rv.FieldByIndexErr(field.index)
+		if err != nil {
+			// the field is inside a nil embedded pointer
+			continue
+		}
+
+		if field.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		prepared, _, err := prepareNode(appID, childPath(kp, field.name), fv.Interface(), policy)
+		if err != nil {
+			return nil, err
+		}
+		if prepared != nil {
+			result[field.name] = prepared
+		}
+	}
+	return result, nil
+}
+
+// structField describes how a struct field is stored.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+}
+
+// structFields returns the stored fields of a struct type.
+//
+// Field names come from the `plist` tag, falling back to the `json` tag and
+// then the Go field name. A name of "-" skips the field, and the "omitempty"
+// option skips zero values. Fields of embedded structs without a name, and of
+// struct fields with the "inline" option, are promoted into the parent.
+//
+// When promoted fields share a name, the rules of encoding/json apply: the
+// shallowest field wins, then a tagged field at that depth, and any other
+// conflict omits the name entirely.
+func structFields(t reflect.Type) []structField {
+	fields := collectFields(t, make(map[reflect.Type]bool))
+
+	var result []structField
+	seen := make(map[string]bool)
+
+	for _, field := range fields {
+		if seen[field.name] {
+			continue
+		}
+		seen[field.name] = true
+
+		if dominant, ok := dominantField(fields, field.name); ok {
+			result = append(result, dominant)
+		}
+	}
+
+	return result
+}
+
+// collectFields returns every stored field of a struct type, including
+// promoted fields that may conflict. Struct types already being expanded are
+// skipped, so recursive embedding terminates.
+func collectFields(t reflect.Type, visiting map[reflect.Type]bool) []structField {
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var fields []structField
+
+	for idx := range t.NumField() {
+		sf := t.Field(idx)
+
+		name, opts, tagged := fieldTag(sf)
+		if name == "-" && len(opts) == 0 {
+			continue
+		}
+
+		// promote the fields of untagged embedded structs and inline fields
+		if (sf.Anonymous && !tagged) || slices.Contains(opts, "inline") {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, embedded := range collectFields(ft, visiting) {
+					embedded.index = append([]int{idx}, embedded.index...)
+					fields = append(fields, embedded)
+				}
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, structField{
+			name:      name,
+			index:     []int{idx},
+			omitEmpty: slices.Contains(opts, "omitempty"),
+			tagged:    tagged,
+		})
+	}
+
+	return fields
+}
+
+// dominantField returns the field that is stored under name, or false if the
+// fields with that name conflict.
+func dominantField(fields []structField, name string) (structField, bool) {
+	var candidates []structField
+	for _, field := range fields {
+		if field.name != name {
+			continue
+		}
+
+		switch {
+		case len(candidates) == 0 || len(field.index) < len(candidates[0].index):
+			candidates = []structField{field}
+		case len(field.index) == len(candidates[0].index):
+			candidates = append(candidates, field)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	var tagged []structField
+	for _, field := range candidates {
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+
+	return structField{}, false
+}
+
+// fieldTag returns the name and options from the `plist` or `json` tag of a
+// field, and whether a name was given.
+func fieldTag(sf reflect.StructField) (string, []string, bool) {
+	tag, ok := sf.Tag.Lookup("plist")
+	if !ok {
+		tag, ok = sf.Tag.Lookup("json")
+	}
+	if !ok {
+		return "", nil, false
+	}
+
+	name, opts, found := strings.Cut(tag, ",")
+	if !found {
+		return name, nil, name != ""
+	}
+	return name, strings.Split(opts, ","), name != ""
+}
+
+// isEmptyValue reports whether a value is empty for the "omitempty" option.
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
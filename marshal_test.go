@@ -0,0 +1,290 @@
+package cfprefs
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+type marshalLevel int8
+
+type marshalServer struct {
+	Host    string        `plist:"host"`
+	Port    uint16        `json:"port"`
+	Tags    []string      `plist:"tags,omitempty"`
+	Timeout time.Duration `plist:"timeout"`
+	Addr    net.IP        `plist:"addr"`
+	Level   marshalLevel
+	Backup  *marshalServer `plist:"backup"`
+	Skipped string         `plist:"-"`
+	hidden  string
+}
+
+type marshalConfig struct {
+	marshalServer
+	Name    string            `plist:"name"`
+	Limits  map[string]int    `plist:"limits"`
+	Servers []marshalServer   `plist:"servers"`
+	Labels  map[string]string `plist:"labels,omitempty"`
+}
+
+func TestPrepareValue(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	host := "backup.example.com"
+
+	testCases := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{name: "string slice", value: []string{"a", "b"}, expected: []any{"a", "b"}},
+		{name: "int array", value: [2]int{1, 2}, expected: []any{1, 2}},
+		{name: "byte array", value: [3]byte{1, 2, 3}, expected: []byte{1, 2, 3}},
+		{name: "int map", value: map[string]int{"a": 1}, expected: map[string]any{"a": 1}},
+		{name: "named type", value: marshalLevel(3), expected: int8(3)},
+		{name: "pointer", value: &host, expected: host},
+		{name: "nil pointer", value: (*string)(nil), expected: nil},
+		{name: "text marshaler", value: net.ParseIP("10.0.0.1"), expected: "10.0.0.1"},
+		{name: "nested any", value: []any{map[string]bool{"on": true}}, expected: []any{map[string]any{"on": true}}},
+		{
+			name: "struct",
+			value: marshalServer{
+				Host:    "localhost",
+				Port:    8080,
+				Timeout: time.Second,
+				Addr:    net.ParseIP("127.0.0.1"),
+				Level:   2,
+				Skipped: "skipped",
+				hidden:  "hidden",
+			},
+			expected: map[string]any{
+				"host":    "localhost",
+				"port":    uint16(8080),
+				"timeout": int64(time.Second),
+				"addr":    "127.0.0.1",
+				"Level":   int8(2),
+			},
+		},
+		{
+			name: "embedded struct",
+			value: marshalConfig{
+				marshalServer: marshalServer{Host: "primary", Tags: []string{"main"}},
+				Name:          "config",
+				Limits:        map[string]int{"max": 10},
+				Servers:       []marshalServer{{Host: "secondary", Backup: &marshalServer{Host: host}}},
+			},
+			expected: map[string]any{
+				"host":    "primary",
+				"port":    uint16(0),
+				"tags":    []any{"main"},
+				"timeout": int64(0),
+				"Level":   int8(0),
+				"name":    "config",
+				"limits":  map[string]any{"max": 10},
+				"servers": []any{
+					map[string]any{
+						"host":    "secondary",
+						"port":    uint16(0),
+						"timeout": int64(0),
+						"Level":   int8(0),
+						"backup": map[string]any{
+							"host":    host,
+							"port":    uint16(0),
+							"timeout": int64(0),
+							"Level":   int8(0),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prepared, err := prepareValue(appID, Path("prepare-test"), tc.value)
+			testutil.AssertNoError(t, err, "prepare value")
+
+			if !reflect.DeepEqual(prepared, tc.expected) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, prepared)
+			}
+		})
+	}
+}
+
+func TestPrepareValueUnsupported(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	testCases := []struct {
+		name  string
+		value any
+		key   string
+	}{
+		{name: "channel", value: make(chan int), key: "unsupported"},
+		{name: "int keys", value: map[int]string{1: "one"}, key: "unsupported"},
+		{name: "nested func", value: map[string]any{"list": []any{"ok", func() {}}}, key: "unsupported/list/1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := prepareValue(appID, Path("unsupported"), tc.value)
+			if !errors.Is(err, ErrTypeMismatch) {
+				t.Fatalf("expected ErrTypeMismatch, got %v", err)
+			}
+
+			var tmErr *TypeMismatchErr
+			if !errors.As(err, &tmErr) || tmErr.Key != tc.key {
+				t.Fatalf("expected keypath %q, got %v", tc.key, err)
+			}
+		})
+	}
+}
+
+type marshalInner struct {
+	Name  string `plist:"name"`
+	Depth int
+	Tag   string
+	Other string
+}
+
+type marshalTagged struct {
+	Value string `plist:"Other"`
+}
+
+type marshalUntagged struct {
+	Other string
+}
+
+type marshalOuter struct {
+	marshalInner
+	marshalTagged
+	marshalUntagged
+	Name string `plist:"name"`
+	Tag  string `plist:"tag"`
+}
+
+func TestStructFieldDominance(t *testing.T) {
+	value := marshalOuter{
+		marshalInner:    marshalInner{Name: "inner", Depth: 1, Tag: "inner tag", Other: "inner other"},
+		marshalTagged:   marshalTagged{Value: "tagged"},
+		marshalUntagged: marshalUntagged{Other: "untagged"},
+		Name:            "outer",
+		Tag:             "outer tag",
+	}
+
+	prepared, err := prepareValue("com.jheddings.cfprefs.testing", Path("dominance"), value)
+	testutil.AssertNoError(t, err, "prepare value")
+
+	// the shallower field wins, then the tagged field at the same depth
+	expected := map[string]any{
+		"name":  "outer",
+		"Depth": 1,
+		"Tag":   "inner tag",
+		"tag":   "outer tag",
+		"Other": "tagged",
+	}
+	if !reflect.DeepEqual(prepared, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, prepared)
+	}
+
+	// untagged fields at the same depth hide each other
+	type conflict struct {
+		marshalInner
+		marshalUntagged
+	}
+
+	prepared, err = prepareValue("com.jheddings.cfprefs.testing", Path("dominance"), conflict{})
+	testutil.AssertNoError(t, err, "prepare conflicting value")
+	if _, ok := prepared.(map[string]any)["Other"]; ok {
+		t.Fatalf("expected conflicting fields to be omitted, got %#v", prepared)
+	}
+}
+
+type marshalNode struct {
+	Name string       `plist:"name"`
+	Next *marshalNode `plist:"next"`
+}
+
+type marshalRecursive struct {
+	*marshalRecursive
+	Name string `plist:"name"`
+}
+
+func TestPrepareValueCycle(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	node := &marshalNode{Name: "a"}
+	node.Next = &marshalNode{Name: "b", Next: node}
+
+	_, err := prepareValue(appID, Path("cycle"), node)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch, got %v", err)
+	}
+
+	var tmErr *TypeMismatchErr
+	if !errors.As(err, &tmErr) || tmErr.Key != "cycle/next/next" {
+		t.Fatalf("expected the keypath of the cycle, got %v", err)
+	}
+
+	// plain maps and slices that contain themselves are also cycles
+	m := map[string]any{}
+	m["self"] = m
+	err = Set(appID, "cycle-map", m)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch for a map cycle, got %v", err)
+	}
+	assertKeyExists(t, appID, "cycle-map", false)
+
+	list := []any{nil}
+	list[0] = list
+	_, err = prepareValue(appID, Path("cycle"), map[string]any{"list": list})
+	if !errors.As(err, &tmErr) || tmErr.Key != "cycle/list/0" {
+		t.Fatalf("expected a cycle error for a slice, got %v", err)
+	}
+
+	// the same pointer may appear more than once without a cycle
+	shared := &marshalNode{Name: "shared"}
+	prepared, err := prepareValue(appID, Path("cycle"), []*marshalNode{shared, shared})
+	testutil.AssertNoError(t, err, "prepare shared pointer")
+
+	expected := []any{map[string]any{"name": "shared"}, map[string]any{"name": "shared"}}
+	if !reflect.DeepEqual(prepared, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, prepared)
+	}
+
+	// recursive embedding does not expand forever
+	prepared, err = prepareValue(appID, Path("cycle"), marshalRecursive{Name: "root"})
+	testutil.AssertNoError(t, err, "prepare recursive embedding")
+	if !reflect.DeepEqual(prepared, map[string]any{"name": "root"}) {
+		t.Fatalf("unexpected value: %#v", prepared)
+	}
+}
+
+func TestSetTypedValues(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	err := Set(appID, "typed-test", marshalConfig{
+		Name:    "config",
+		Servers: []marshalServer{{Host: "localhost", Port: 8080}},
+	})
+	testutil.AssertNoError(t, err, "set struct")
+	defer Delete(appID, "typed-test")
+
+	value, err := Get(appID, "typed-test/servers/0/port")
+	testutil.AssertNoError(t, err, "get nested field")
+	if value != int32(8080) {
+		t.Fatalf("expected 8080, got %v [%T]", value, value)
+	}
+
+	err = Set(appID, "typed-test/hosts", []string{"a", "b"})
+	testutil.AssertNoError(t, err, "set typed slice")
+
+	hosts, err := GetSlice(appID, "typed-test/hosts")
+	testutil.AssertNoError(t, err, "get typed slice")
+	if !reflect.DeepEqual(hosts, []any{"a", "b"}) {
+		t.Fatalf("expected [a b], got %v", hosts)
+	}
+}
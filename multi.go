@@ -0,0 +1,133 @@
+package cfprefs
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// GetMultiple retrieves the preference values for several keypaths at once.
+//
+// Keypaths are grouped by their top-level key, so each top-level value is
+// fetched and converted only once, regardless of how many nested values are
+// requested from it.
+//
+// Example usage:
+//
+//	values, err := GetMultiple("com.example.app", "config/server/host", "config/server/port", "username")
+//	host := values["config/server/host"]
+//
+// The result is keyed by the keypaths as given. Top-level keys are read from
+// the full domain hierarchy, like Get. Keypaths that do not exist are omitted
+// from the result. An error is returned if a keypath is invalid or runs
+// through a value that is not a container, in which case the error names the
+// keypath, or if the values cannot be read.
+func GetMultiple(appID string, keypaths ...string) (_ map[string]any, err error) {
+	defer wrapError(&err, "get", appID, "")
+
+	parsed := make([]KeyPath, len(keypaths))
+	roots := make(map[string]any)
+
+	for i, keypath := range keypaths {
+		kp, err := parseKeypath(keypath)
+		if err != nil {
+			err = NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+			wrapError(&err, "get", appID, keypath)
+			return nil, err
+		}
+
+		parsed[i] = kp
+		roots[kp.Key()] = nil
+	}
+
+	// read each top-level key once, the same way as Get
+	for _, key := range slices.Sorted(maps.Keys(roots)) {
+		value, exists, err := readRoot(context.Background(), appID, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			roots[key] = value
+		} else {
+			delete(roots, key)
+		}
+	}
+
+	result := make(map[string]any, len(parsed))
+
+	for i, kp := range parsed {
+		root, ok := roots[kp.Key()]
+		if !ok {
+			continue
+		}
+
+		value, err := getValueAtPath(root, kp.tokens)
+		if pathMissing(err) {
+			continue
+		}
+		if err != nil {
+			wrapError(&err, "get", appID, keypaths[i])
+			return nil, err
+		}
+
+		result[keypaths[i]] = value
+	}
+
+	return result, nil
+}
+
+// SetMultiple writes several preference values at once, synchronizing only
+// once after all values have been written.
+//
+// Keypaths are grouped by their top-level key, so each top-level value is
+// fetched, modified and written only once. Keypaths are applied in sorted
+// order, which matters only when they overlap or append to the same array.
+//
+// Example usage:
+//
+//	err := SetMultiple("com.example.app", map[string]any{
+//		"config/server/host": "localhost",
+//		"config/server/port": 8080,
+//		"username":           "john_doe",
+//	})
+//
+// No values are written if any keypath is invalid or cannot be set, or if a
+// write hook or middleware rejects any of them.
+func SetMultiple(appID string, values map[string]any) (err error) {
+	defer wrapError(&err, "set", appID, "")
+
+	keypaths := slices.Sorted(maps.Keys(values))
+	parsed := make([]KeyPath, len(keypaths))
+	roots := make(map[string]bool)
+
+	for i, keypath := range keypaths {
+		kp, err := parseKeypath(keypath)
+		if err != nil {
+			return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+		}
+
+		parsed[i] = kp
+		if !kp.IsRoot() {
+			roots[kp.Key()] = true
+		}
+	}
+
+	tx := Begin(appID)
+
+	// read the values being modified before staging any changes
+	if len(roots) > 0 {
+		if err := tx.preload(slices.Collect(maps.Keys(roots))); err != nil {
+			return err
+		}
+	}
+
+	for i, kp := range parsed {
+		if err := tx.SetPath(kp, values[keypaths[i]]); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,111 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestGetMultiple(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	testData := map[string]any{
+		"server": map[string]any{
+			"host": "localhost",
+			"port": int64(8080),
+		},
+		"items": []any{"first", "second"},
+	}
+
+	cleanup := setupTest(t, appID, "multi-get", testData)
+	defer cleanup()
+
+	cleanup = setupTest(t, appID, "multi-user", "john_doe")
+	defer cleanup()
+
+	values, err := GetMultiple(appID,
+		"multi-get/server/host",
+		"multi-get/server/port",
+		"multi-get/items/1",
+		"multi-get/missing",
+		"multi-user",
+		"multi-missing",
+	)
+	testutil.AssertNoError(t, err, "get multiple")
+
+	expected := map[string]any{
+		"multi-get/server/host": "localhost",
+		"multi-get/server/port": int64(8080),
+		"multi-get/items/1":     "second",
+		"multi-user":            "john_doe",
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+
+	_, err = GetMultiple(appID, "multi-user", "")
+	testutil.AssertError(t, err, "invalid keypath")
+
+	// a path through a scalar is an error, not a missing value
+	_, err = GetMultiple(appID, "multi-get/items/5", "multi-user/name")
+	if !errors.Is(err, ErrInvalidKeyPath) || errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrInvalidKeyPath, got %v", err)
+	}
+
+	var perr *Error
+	if !errors.As(err, &perr) || perr.KeyPath != "multi-user/name" {
+		t.Fatalf("expected the error to name the keypath, got %v", err)
+	}
+}
+
+func TestSetMultiple(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "multi-set", map[string]any{"name": "original"})
+	defer cleanup()
+	defer Delete(appID, "multi-set-user")
+
+	err := SetMultiple(appID, map[string]any{
+		"multi-set/server/host": "localhost",
+		"multi-set/server/port": int64(8080),
+		"multi-set/items/~]":    "first",
+		"multi-set-user":        "john_doe",
+	})
+	testutil.AssertNoError(t, err, "set multiple")
+
+	expected := map[string]any{
+		"name":   "original",
+		"server": map[string]any{"host": "localhost", "port": int64(8080)},
+		"items":  []any{"first"},
+	}
+
+	value, err := GetMap(appID, "multi-set")
+	testutil.AssertNoError(t, err, "get modified value")
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("expected %v, got %v", expected, value)
+	}
+
+	user, err := GetStr(appID, "multi-set-user")
+	testutil.AssertNoError(t, err, "get top-level value")
+	if user != "john_doe" {
+		t.Fatalf("expected 'john_doe', got '%s'", user)
+	}
+
+	// nothing is written if any value cannot be set
+	err = SetMultiple(appID, map[string]any{
+		"multi-set/name":           "changed",
+		"multi-set/name/invalid":   "fails",
+		"multi-set-user-untouched": "value",
+	})
+	testutil.AssertError(t, err, "set through scalar")
+
+	name, err := GetStr(appID, "multi-set/name")
+	testutil.AssertNoError(t, err, "get unchanged value")
+	if name != "original" {
+		t.Fatalf("expected 'original', got '%s'", name)
+	}
+	assertKeyExists(t, appID, "multi-set-user-untouched", false)
+}
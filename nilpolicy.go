@@ -0,0 +1,47 @@
+package cfprefs
+
+import "sync/atomic"
+
+// NilPolicy controls how nil values inside arrays and dictionaries are
+// handled when writing.
+//
+// The preferences system cannot store a nil value inside a container. A nil
+// value written directly to a keypath deletes the value at that keypath
+// instead, regardless of the policy. Nil struct fields are always omitted.
+type NilPolicy int32
+
+const (
+	// NilError rejects nil values inside arrays and dictionaries with a
+	// NilValueErr naming the keypath of the value. This is the default.
+	NilError NilPolicy = iota
+
+	// NilOmit removes nil values from arrays and dictionaries. Later array
+	// elements move down to fill the gap.
+	NilOmit
+)
+
+// defaultNilPolicy is the policy used when writing nil values in containers.
+var defaultNilPolicy atomic.Int32
+
+// SetNilPolicy sets how nil values inside arrays and dictionaries are handled
+// by Set and the other write operations. The default is NilError.
+func SetNilPolicy(policy NilPolicy) {
+	defaultNilPolicy.Store(int32(policy))
+}
+
+// GetNilPolicy returns how nil values inside arrays and dictionaries are
+// handled.
+func GetNilPolicy() NilPolicy {
+	return NilPolicy(defaultNilPolicy.Load())
+}
+
+// String returns the name of the nil policy.
+func (p NilPolicy) String() string {
+	switch p {
+	case NilError:
+		return "error"
+	case NilOmit:
+		return "omit"
+	}
+	return "unknown"
+}
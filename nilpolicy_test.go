@@ -0,0 +1,140 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestNilPolicyString(t *testing.T) {
+	if NilError.String() != "error" || NilOmit.String() != "omit" {
+		t.Fatalf("unexpected policy names: %s, %s", NilError, NilOmit)
+	}
+
+	if GetNilPolicy() != NilError {
+		t.Fatalf("expected default policy to be error, got %s", GetNilPolicy())
+	}
+}
+
+func TestPrepareNilError(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	testCases := []struct {
+		name  string
+		value any
+		key   string
+	}{
+		{name: "slice element", value: []any{"a", nil}, key: "nil-test/1"},
+		{name: "map value", value: map[string]any{"a": nil}, key: "nil-test/a"},
+		{name: "nested", value: map[string]any{"list": []any{"ok", nil}}, key: "nil-test/list/1"},
+		{name: "typed slice", value: []*string{nil}, key: "nil-test/0"},
+		{name: "typed map", value: map[string][]int{"empty": nil}, key: "nil-test/empty"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := prepareValue(appID, Path("nil-test"), tc.value)
+			if !errors.Is(err, ErrNilValue) {
+				t.Fatalf("expected ErrNilValue, got %v", err)
+			}
+
+			var nilErr *NilValueErr
+			if !errors.As(err, &nilErr) || nilErr.Key != tc.key || nilErr.AppID != appID {
+				t.Fatalf("expected keypath %q, got %v", tc.key, err)
+			}
+		})
+	}
+
+	// nil struct fields are always omitted
+	type server struct {
+		Host   string  `plist:"host"`
+		Backup *string `plist:"backup"`
+	}
+
+	prepared, err := prepareValue(appID, Path("nil-test"), server{Host: "localhost"})
+	testutil.AssertNoError(t, err, "prepare struct with nil field")
+	if !reflect.DeepEqual(prepared, map[string]any{"host": "localhost"}) {
+		t.Fatalf("expected nil field to be omitted, got %v", prepared)
+	}
+}
+
+func TestPrepareNilOmit(t *testing.T) {
+	defer SetNilPolicy(GetNilPolicy())
+	SetNilPolicy(NilOmit)
+
+	original := map[string]any{
+		"list":  []any{nil, "a", nil, "b"},
+		"empty": nil,
+		"keep":  "value",
+	}
+
+	prepared, err := prepareValue("com.jheddings.cfprefs.testing", Path("nil-test"), original)
+	testutil.AssertNoError(t, err, "prepare with omit policy")
+
+	expected := map[string]any{
+		"list": []any{"a", "b"},
+		"keep": "value",
+	}
+	if !reflect.DeepEqual(prepared, expected) {
+		t.Fatalf("expected %v, got %v", expected, prepared)
+	}
+
+	// the original value is not modified
+	if _, ok := original["empty"]; !ok || len(original["list"].([]any)) != 4 {
+		t.Fatalf("expected original to be unchanged, got %v", original)
+	}
+
+	typed, err := prepareValue("com.jheddings.cfprefs.testing", Path("nil-test"), []*int{nil})
+	testutil.AssertNoError(t, err, "prepare typed slice with omit policy")
+	if !reflect.DeepEqual(typed, []any{}) {
+		t.Fatalf("expected empty list, got %v", typed)
+	}
+}
+
+func TestSetNil(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "nil-set-test", map[string]any{
+		"name":  "value",
+		"items": []any{"a", "b"},
+	})
+	defer cleanup()
+
+	// a nil leaf deletes the nested value
+	err := Set(appID, "nil-set-test/name", nil)
+	testutil.AssertNoError(t, err, "set nested nil")
+	assertKeyExists(t, appID, "nil-set-test/name", false)
+
+	err = Open(appID).Set("nil-set-test/items/0", nil)
+	testutil.AssertNoError(t, err, "set nil through domain")
+
+	items, err := GetSlice(appID, "nil-set-test/items")
+	testutil.AssertNoError(t, err, "get items")
+	if !reflect.DeepEqual(items, []any{"b"}) {
+		t.Fatalf("expected [b], got %v", items)
+	}
+
+	// a nil inside a container is rejected without writing
+	err = Set(appID, "nil-set-test/items", []any{"c", nil})
+	if !errors.Is(err, ErrNilValue) {
+		t.Fatalf("expected ErrNilValue, got %v", err)
+	}
+
+	err = SetMultiple(appID, map[string]any{"nil-set-test/items": nil})
+	testutil.AssertNoError(t, err, "set multiple nil")
+	assertKeyExists(t, appID, "nil-set-test/items", false)
+
+	// a nil leaf at the root deletes the key, and missing keys are ignored
+	err = Set(appID, "nil-set-test", nil)
+	testutil.AssertNoError(t, err, "set root nil")
+	assertKeyExists(t, appID, "nil-set-test", false)
+
+	err = Set(appID, "nil-set-test/missing", nil)
+	testutil.AssertNoError(t, err, "set nil on missing key")
+	assertKeyExists(t, appID, "nil-set-test", false)
+
+	// restore the key for cleanup
+	testutil.AssertNoError(t, Set(appID, "nil-set-test", "done"), "restore key")
+}
@@ -0,0 +1,77 @@
+package cfprefs
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
+// OverflowPolicy controls how unsigned integers that are too large for the
+// preferences system are stored.
+//
+// The preferences system only stores signed numbers, so a uint or uint64
+// greater than math.MaxInt64 has no exact numeric representation.
+type OverflowPolicy int32
+
+const (
+	// OverflowError rejects large unsigned integers with a RangeErr. This is
+	// the default.
+	OverflowError OverflowPolicy = iota
+
+	// OverflowString stores large unsigned integers as a decimal string
+	// (e.g., "18446744073709551615"), which can be read back with
+	// strconv.ParseUint.
+	OverflowString
+
+	// OverflowData stores large unsigned integers as 8 bytes of data in
+	// big-endian order, which can be read back with binary.BigEndian.Uint64.
+	OverflowData
+)
+
+// defaultOverflowPolicy is the policy used when writing large unsigned integers.
+var defaultOverflowPolicy atomic.Int32
+
+// SetOverflowPolicy sets how unsigned integers greater than math.MaxInt64
+// are stored by Set and the other write operations. The default is
+// OverflowError.
+func SetOverflowPolicy(policy OverflowPolicy) {
+	defaultOverflowPolicy.Store(int32(policy))
+}
+
+// GetOverflowPolicy returns how unsigned integers greater than math.MaxInt64
+// are stored.
+func GetOverflowPolicy() OverflowPolicy {
+	return OverflowPolicy(defaultOverflowPolicy.Load())
+}
+
+// String returns the name of the overflow policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowError:
+		return "error"
+	case OverflowString:
+		return "string"
+	case OverflowData:
+		return "data"
+	}
+	return "unknown"
+}
+
+// prepareUint applies the overflow policy to an unsigned integer.
+func prepareUint(appID string, kp KeyPath, original any, n uint64, policy OverflowPolicy) (any, bool, error) {
+	if n <= math.MaxInt64 {
+		return original, false, nil
+	}
+
+	switch policy {
+	case OverflowString:
+		return strconv.FormatUint(n, 10), true, nil
+
+	case OverflowData:
+		return binary.BigEndian.AppendUint64(nil, n), true, nil
+	}
+
+	return nil, false, NewRangeError(original).WithKey(appID, kp.String()).
+		WithMsg("exceeds the largest signed 64-bit integer")
+}
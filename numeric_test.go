@@ -0,0 +1,120 @@
+package cfprefs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestOverflowError(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	// values that fit are stored as numbers
+	err := Set(appID, "overflow-test/counter", uint64(math.MaxInt64))
+	testutil.AssertNoError(t, err, "set largest signed value")
+	defer Delete(appID, "overflow-test")
+
+	value, err := Get(appID, "overflow-test/counter")
+	testutil.AssertNoError(t, err, "get largest signed value")
+	if value != int64(math.MaxInt64) {
+		t.Fatalf("expected %d, got %v", int64(math.MaxInt64), value)
+	}
+
+	// larger values are rejected with the keypath of the offending element
+	err = Set(appID, "overflow-test", map[string]any{
+		"items": []any{uint64(1), uint64(math.MaxUint64)},
+	})
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+
+	var rangeErr *RangeErr
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("expected RangeErr, got %T", err)
+	}
+	if rangeErr.AppID != appID || rangeErr.Key != "overflow-test/items/1" {
+		t.Fatalf("expected keypath 'overflow-test/items/1', got %q", rangeErr.Key)
+	}
+
+	// the rejected write must not have changed the stored value
+	value, err = Get(appID, "overflow-test/counter")
+	testutil.AssertNoError(t, err, "get unchanged value")
+	if value != int64(math.MaxInt64) {
+		t.Fatalf("expected %d, got %v", int64(math.MaxInt64), value)
+	}
+}
+
+func TestOverflowFallback(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	defer SetOverflowPolicy(GetOverflowPolicy())
+
+	input := map[string]any{"counter": uint64(math.MaxUint64), "small": uint64(1)}
+	original := deepCopy(input)
+
+	t.Run("String", func(t *testing.T) {
+		SetOverflowPolicy(OverflowString)
+
+		err := Set(appID, "overflow-string", input)
+		testutil.AssertNoError(t, err, "set with string fallback")
+		defer Delete(appID, "overflow-string")
+
+		value, err := Get(appID, "overflow-string/counter")
+		testutil.AssertNoError(t, err, "get fallback value")
+		if value != "18446744073709551615" {
+			t.Fatalf("expected decimal string, got %v", value)
+		}
+
+		value, err = Get(appID, "overflow-string/small")
+		testutil.AssertNoError(t, err, "get small value")
+		if value != int64(1) {
+			t.Fatalf("expected 1, got %v", value)
+		}
+	})
+
+	t.Run("Data", func(t *testing.T) {
+		SetOverflowPolicy(OverflowData)
+
+		err := Set(appID, "overflow-data", input)
+		testutil.AssertNoError(t, err, "set with data fallback")
+		defer Delete(appID, "overflow-data")
+
+		value, err := GetValue(appID, "overflow-data/counter")
+		testutil.AssertNoError(t, err, "get fallback value")
+
+		data, ok := value.Interface().([]byte)
+		if !ok || binary.BigEndian.Uint64(data) != math.MaxUint64 {
+			t.Fatalf("expected big-endian data, got %v", value)
+		}
+	})
+
+	// the caller's value is never modified by the fallback
+	if !reflect.DeepEqual(input, original) {
+		t.Fatalf("expected input to be unchanged, got %v", input)
+	}
+}
+
+func TestValueRange(t *testing.T) {
+	_, err := ValueOf(uint64(math.MaxUint64))
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+
+	var value Value
+
+	err = value.UnmarshalJSON([]byte(`{"kind":"integer","width":8,"value":300}`))
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange for int8, got %v", err)
+	}
+
+	err = value.UnmarshalJSON([]byte(`{"kind":"integer","width":16,"value":-32768}`))
+	testutil.AssertNoError(t, err, "smallest int16")
+
+	err = value.UnmarshalJSON([]byte(`{"kind":"real","width":32,"value":1e300}`))
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange for float32, got %v", err)
+	}
+}
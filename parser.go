@@ -1,50 +1,146 @@
 package cfprefs
 
 import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/go-openapi/jsonpointer"
 )
 
-// keypath represents a key and an optional JSON Pointer path.
-type keypath struct {
-	Key  string
-	Path string
+// KeyPath is a precompiled keypath, consisting of a top-level preference key
+// and an optional list of decoded JSON Pointer tokens into its value.
+//
+// A KeyPath may be constructed once and reused across calls to GetPath,
+// SetPath, DeletePath and ExistsPath, avoiding repeated parsing.
+type KeyPath struct {
+	key    string
+	tokens []string
 }
 
-// newKeypath creates a new keypath with the given key and path.
-func newKeypath(pref, path string) *keypath {
-	return &keypath{Key: pref, Path: path}
+// Path creates a KeyPath from a top-level key and a list of segments.
+//
+// Segments must be strings (object keys) or integers (array indices), and
+// Path panics if any segment has another type. Segments are used verbatim, so
+// keys containing "/" or "~" do not need to be escaped, and the top-level key
+// may itself contain "/".
+//
+// Example usage:
+//
+//	// equivalent to "config/servers/0/host"
+//	kp := Path("config", "servers", 0, "host")
+//
+//	// a top-level key that contains a slash
+//	kp := Path("my/key", "enabled")
+func Path(key string, segments ...any) KeyPath {
+	kp := KeyPath{key: key}
+	return kp.Child(segments...)
 }
 
-// parseKeypath parses a keypath string into a keypath struct.
-func parseKeypath(keypath string) (*keypath, error) {
+// ParseKeyPath parses a keypath string into a KeyPath.
+//
+// The keypath is a top-level key optionally followed by a JSON Pointer path
+// (e.g., "config/server/port"). The key ends at the first "/", and the key
+// and the remainder are decoded according to RFC 6901, so a key containing
+// "/" is written as "~1" and "~" as "~0".
+func ParseKeyPath(keypath string) (KeyPath, error) {
 	if keypath == "" {
-		return nil, NewKeyPathError().WithMsg("invalid keypath; empty string")
+		return KeyPath{}, NewKeyPathError().WithMsg("invalid keypath; empty string")
 	}
 
 	if strings.HasPrefix(keypath, "/") {
-		return nil, NewKeyPathError().WithMsg("invalid keypath; empty pref")
+		return KeyPath{}, NewKeyPathError().WithMsg("invalid keypath; empty pref")
+	}
+
+	key, path, found := strings.Cut(keypath, "/")
+
+	key = jsonpointer.Unescape(key)
+
+	// a trailing slash refers to the root value
+	if !found || path == "" {
+		return KeyPath{key: key}, nil
 	}
 
-	parts := strings.SplitN(keypath, "/", 2)
+	ptr, err := jsonpointer.New("/" + path)
+	if err != nil {
+		return KeyPath{}, NewKeyPathError().Wrap(err).WithMsgF("invalid pointer: /%s", path)
+	}
+
+	return KeyPath{key: key, tokens: ptr.DecodedTokens()}, nil
+}
+
+// Key returns the top-level preference key.
+func (k KeyPath) Key() string {
+	return k.key
+}
+
+// Tokens returns the decoded pointer tokens below the top-level key.
+func (k KeyPath) Tokens() []string {
+	return slices.Clone(k.tokens)
+}
+
+// Child returns a new KeyPath with the given segments appended. Like Path, it
+// panics if a segment is not a string or an integer.
+func (k KeyPath) Child(segments ...any) KeyPath {
+	tokens := make([]string, len(k.tokens), len(k.tokens)+len(segments))
+	copy(tokens, k.tokens)
 
-	if len(parts) < 2 {
-		return newKeypath(parts[0], ""), nil
+	for _, segment := range segments {
+		token, ok := segmentToken(segment)
+		if !ok {
+			panic(fmt.Sprintf("cfprefs: keypath segment must be a string or integer, got %T", segment))
+		}
+		tokens = append(tokens, token)
 	}
 
-	path := parts[1]
-	if path != "" {
-		path = "/" + path
+	return KeyPath{key: k.key, tokens: tokens}
+}
+
+// segmentToken returns the token for a string or integer segment.
+func segmentToken(segment any) (string, bool) {
+	switch s := segment.(type) {
+	case string:
+		return s, true
+	case int:
+		return strconv.Itoa(s), true
+	case int8, int16, int32, int64:
+		return strconv.FormatInt(reflect.ValueOf(s).Int(), 10), true
+	case uint, uint8, uint16, uint32, uint64:
+		return strconv.FormatUint(reflect.ValueOf(s).Uint(), 10), true
 	}
+	return "", false
+}
 
-	return newKeypath(parts[0], path), nil
+// IsRoot returns true if the keypath refers to the entire top-level value.
+func (k KeyPath) IsRoot() bool {
+	return len(k.tokens) == 0
 }
 
-// String returns the keypath as a string.
-func (k *keypath) String() string {
-	return k.Key + k.Path
+// Pointer returns the escaped JSON Pointer portion of the keypath, or an
+// empty string if the keypath refers to the root value.
+func (k KeyPath) Pointer() string {
+	var sb strings.Builder
+	for _, token := range k.tokens {
+		sb.WriteString("/")
+		sb.WriteString(jsonpointer.Escape(token))
+	}
+	return sb.String()
 }
 
-// IsRoot returns true if the keypath is a root keypath (empty pointer).
-func (k *keypath) IsRoot() bool {
-	return k.Path == "" || k.Path == "/"
+// String returns the keypath as a string in pointer syntax, which parses
+// back into the same KeyPath with ParseKeyPath. The top-level key is escaped
+// like the pointer tokens.
+func (k KeyPath) String() string {
+	return jsonpointer.Escape(k.key) + k.Pointer()
+}
+
+// childPath returns the keypath of a child value. A KeyPath without a key
+// refers to the whole domain, so its children are top-level keys.
+func childPath(kp KeyPath, segment any) KeyPath {
+	if kp.Key() == "" {
+		return Path(fmt.Sprint(segment))
+	}
+	return kp.Child(segment)
 }
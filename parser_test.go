@@ -0,0 +1,152 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestParseKeyPath(t *testing.T) {
+	testCases := []struct {
+		keypath string
+		key     string
+		tokens  []string
+	}{
+		{keypath: "settings", key: "settings"},
+		{keypath: "settings/", key: "settings"},
+		{keypath: "user/name", key: "user", tokens: []string{"name"}},
+		{keypath: "items/0/name", key: "items", tokens: []string{"0", "name"}},
+		{keypath: "paths/a~1b/m~0n", key: "paths", tokens: []string{"a/b", "m~n"}},
+		{keypath: "my~1key/a", key: "my/key", tokens: []string{"a"}},
+		{keypath: "m~0n", key: "m~n"},
+		{keypath: "my~01key", key: "my~1key"},
+		{keypath: "a~b/c", key: "a~b", tokens: []string{"c"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.keypath, func(t *testing.T) {
+			kp, err := ParseKeyPath(tc.keypath)
+			testutil.AssertNoError(t, err, "parse keypath")
+
+			if kp.Key() != tc.key {
+				t.Fatalf("expected key %q, got %q", tc.key, kp.Key())
+			}
+			if len(tc.tokens) == 0 && !kp.IsRoot() {
+				t.Fatalf("expected root keypath, got %v", kp.Tokens())
+			}
+			if len(tc.tokens) > 0 && !reflect.DeepEqual(kp.Tokens(), tc.tokens) {
+				t.Fatalf("expected tokens %v, got %v", tc.tokens, kp.Tokens())
+			}
+		})
+	}
+
+	for _, keypath := range []string{"", "/settings"} {
+		_, err := ParseKeyPath(keypath)
+		if !errors.Is(err, ErrInvalidKeyPath) {
+			t.Fatalf("expected ErrInvalidKeyPath for %q, got %v", keypath, err)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	kp := Path("config", "servers", 0, "a/b")
+
+	if kp.String() != "config/servers/0/a~1b" {
+		t.Fatalf("unexpected keypath string: %s", kp.String())
+	}
+
+	parsed, err := ParseKeyPath(kp.String())
+	testutil.AssertNoError(t, err, "parse keypath string")
+	if !reflect.DeepEqual(parsed, kp) {
+		t.Fatalf("expected %v, got %v", kp, parsed)
+	}
+
+	child := kp.Child("port")
+	if child.String() != "config/servers/0/a~1b/port" {
+		t.Fatalf("unexpected child string: %s", child.String())
+	}
+	if kp.String() != "config/servers/0/a~1b" {
+		t.Fatalf("parent keypath was modified: %s", kp.String())
+	}
+
+	// the top-level key is escaped, so the string parses back
+	for _, kp := range []KeyPath{Path("my/key", "enabled"), Path("m~n"), Path("a~1b", uint8(1), int64(2))} {
+		parsed, err := ParseKeyPath(kp.String())
+		testutil.AssertNoError(t, err, "parse keypath string")
+		if parsed.Key() != kp.Key() || !slices.Equal(parsed.Tokens(), kp.Tokens()) {
+			t.Fatalf("expected %v to round trip, got %v", kp, parsed)
+		}
+	}
+}
+
+func TestPathInvalidSegment(t *testing.T) {
+	for _, segment := range []any{1.5, struct{}{}, nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for segment %#v", segment)
+				}
+			}()
+			Path("config", segment)
+		}()
+	}
+}
+
+func TestKeyPathOperations(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	// a top-level key containing a slash is escaped in a keypath string
+	kp := Path("keypath/test", "server", "port")
+
+	err := SetPath(appID, kp, int64(8080))
+	testutil.AssertNoError(t, err, "set path")
+	defer DeletePath(appID, Path("keypath/test"))
+
+	exists, err := ExistsPath(appID, kp)
+	testutil.AssertNoError(t, err, "check path exists")
+	if !exists {
+		t.Fatal("expected path to exist")
+	}
+
+	value, err := GetPath(appID, kp)
+	testutil.AssertNoError(t, err, "get path")
+	if value != int64(8080) {
+		t.Fatalf("expected 8080, got %v", value)
+	}
+
+	err = DeletePath(appID, kp)
+	testutil.AssertNoError(t, err, "delete path")
+
+	exists, err = ExistsPath(appID, kp)
+	testutil.AssertNoError(t, err, "check path deleted")
+	if exists {
+		t.Fatal("expected path to be deleted")
+	}
+
+	exists, err = ExistsPath(appID, Path("keypath/test", "server"))
+	testutil.AssertNoError(t, err, "check parent exists")
+	if !exists {
+		t.Fatal("expected parent to remain")
+	}
+
+	exists, err = Exists(appID, "keypath~1test/server")
+	testutil.AssertNoError(t, err, "check escaped keypath")
+	if !exists {
+		t.Fatal("expected the escaped keypath to address the same key")
+	}
+
+	// a key that literally contains "~1" needs its "~" escaped
+	err = SetPath(appID, Path("keypath~1literal"), "value")
+	testutil.AssertNoError(t, err, "set literal key")
+	defer DeletePath(appID, Path("keypath~1literal"))
+
+	literal, err := Get(appID, "keypath~01literal")
+	testutil.AssertNoError(t, err, "get literal key")
+	if literal != "value" {
+		t.Fatalf("expected 'value', got %v", literal)
+	}
+	assertKeyExists(t, appID, "keypath~1literal", false)
+}
@@ -0,0 +1,64 @@
+package cfprefs
+
+import (
+	"slices"
+	"sync"
+)
+
+// registry holds functions registered for each domain.
+type registry[T any] struct {
+	mu      sync.RWMutex
+	entries map[string][]*registryEntry[T]
+}
+
+// registryEntry gives each registration a unique identity for removal.
+type registryEntry[T any] struct {
+	fn T
+}
+
+// add registers fn for the given appID, returning a function that removes it.
+// Removing more than once has no effect.
+func (r *registry[T]) add(appID string, fn T) (remove func()) {
+	entry := &registryEntry[T]{fn: fn}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.entries == nil {
+		r.entries = make(map[string][]*registryEntry[T])
+	}
+
+	// entries are replaced rather than modified, so readers can iterate freely
+	r.entries[appID] = append(slices.Clip(r.entries[appID]), entry)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			entries := slices.DeleteFunc(slices.Clone(r.entries[appID]), func(e *registryEntry[T]) bool {
+				return e == entry
+			})
+			if len(entries) == 0 {
+				delete(r.entries, appID)
+			} else {
+				r.entries[appID] = entries
+			}
+		})
+	}
+}
+
+// get returns the functions registered for the given appID, in the order
+// they were registered.
+func (r *registry[T]) get(appID string) []T {
+	r.mu.RLock()
+	entries := r.entries[appID]
+	r.mu.RUnlock()
+
+	fns := make([]T, len(entries))
+	for i, entry := range entries {
+		fns[i] = entry.fn
+	}
+	return fns
+}
@@ -0,0 +1,195 @@
+package cfprefs
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/internal"
+)
+
+// Save writes the fields of a struct, or the entries of a map with string keys,
+// to the given appID as top-level keys.
+//
+// Only keys whose stored value differs are written, and all changes are
+// applied with a single synchronize. Stored values are read the same way as
+// Load, so saving a loaded value without changes writes nothing. Each changed key passes through the
+// write hooks and middleware for the domain, and nothing is written if any
+// change is rejected. Struct fields that are absent, such as
+// nil pointers or empty fields tagged "omitempty", delete their key if it
+// exists. Keys that do not correspond to a field are left untouched.
+//
+// Fields are named using the same tags as Set and Load. Dates are considered
+// unchanged if they differ by less than a microsecond, since the preferences
+// system does not store them with full precision.
+//
+// Example usage:
+//
+//	var cfg Config
+//	err := Load("com.example.app", &cfg)
+//	cfg.Username = "jane"
+//	err = Save("com.example.app", &cfg)
+func Save(appID string, v any) (err error) {
+	defer wrapError(&err, "save", appID, "")
+
+	names, err := saveKeys(appID, v)
+	if err != nil {
+		return err
+	}
+
+	prepared, err := prepareValue(appID, KeyPath{}, v)
+	if err != nil {
+		return err
+	}
+
+	desired, _ := prepared.(map[string]any)
+
+	// map entries are only written, since there are no fields to delete
+	if names == nil {
+		names = slices.Sorted(maps.Keys(desired))
+	}
+
+	// read the current values the same way as Load, so a loaded struct that
+	// is saved unchanged writes nothing
+	current := make(map[string]any, len(names))
+	for _, name := range names {
+		value, exists, err := readRoot(context.Background(), appID, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			current[name] = value
+		}
+	}
+
+	changed, remove := diffValues(names, desired, current)
+	if len(changed) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	return runWrites(context.Background(), appID, saveOps(appID, changed, remove, current), func() error {
+		if err := internal.SetMultiple(appID, changed, remove); err != nil {
+			return NewInternalError().Wrap(err).WithMsgF("failed to save: %s", appID)
+		}
+		return nil
+	})
+}
+
+// saveOps returns the write operations for the keys changed by Save, in
+// order of their keys.
+func saveOps(appID string, changed map[string]any, remove []string, current map[string]any) []WriteOp {
+	keys := append(slices.Collect(maps.Keys(changed)), remove...)
+	slices.Sort(keys)
+
+	ops := make([]WriteOp, len(keys))
+	for i, key := range keys {
+		ops[i] = WriteOp{AppID: appID, KeyPath: Path(key), Old: current[key], New: changed[key]}
+	}
+
+	return ops
+}
+
+// saveKeys returns the top-level keys managed by a struct, or nil for a map.
+func saveKeys(appID string, v any) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		var names []string
+		for _, field := range structFields(rv.Type()) {
+			if !slices.Contains(names, field.name) {
+				names = append(names, field.name)
+			}
+		}
+		slices.Sort(names)
+		return names, nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return nil, nil
+		}
+	}
+
+	return nil, NewTypeMismatchError(nil, v).WithKey(appID, "")
+}
+
+// diffValues compares the desired values for the given keys with the current
+// values, returning the values to write and the keys to remove.
+func diffValues(names []string, desired, current map[string]any) (map[string]any, []string) {
+	changed := make(map[string]any)
+	var remove []string
+
+	for _, name := range names {
+		want, wanted := desired[name]
+		have, exists := current[name]
+
+		if !wanted {
+			if exists {
+				remove = append(remove, name)
+			}
+			continue
+		}
+
+		if exists && valuesEqual(have, want) {
+			continue
+		}
+
+		changed[name] = want
+	}
+
+	return changed, remove
+}
+
+// valuesEqual reports whether a stored value matches a value about to be
+// written. Numbers are compared by value regardless of their width.
+func valuesEqual(a, b any) bool {
+	if x, ok := toFloat64(a); ok {
+		y, ok := toFloat64(b)
+		if !ok {
+			return false
+		}
+
+		// compare integers exactly, since large values lose precision as floats
+		xi, xok := toInt64(a)
+		yi, yok := toInt64(b)
+		if xok && yok {
+			return xi == yi
+		}
+		return x == y
+	}
+
+	switch x := a.(type) {
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Sub(y).Abs() < time.Microsecond
+
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, valuesEqual)
+
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, xv := range x {
+			yv, exists := y[key]
+			if !exists || !valuesEqual(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
@@ -0,0 +1,115 @@
+package cfprefs
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestDiffValues(t *testing.T) {
+	now := time.Now()
+
+	names := []string{"count", "created", "gone", "name", "items", "unset"}
+	desired := map[string]any{
+		"count":   8080,
+		"created": now,
+		"name":    "updated",
+		"items":   []any{"a", uint16(2)},
+	}
+	current := map[string]any{
+		"count":   int32(8080),
+		"created": now.Add(100 * time.Nanosecond),
+		"gone":    "stale",
+		"name":    "original",
+		"items":   []any{"a", int64(2)},
+		"other":   "untouched",
+	}
+
+	changed, remove := diffValues(names, desired, current)
+
+	expected := map[string]any{"name": "updated"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected changes %v, got %v", expected, changed)
+	}
+
+	if !slices.Equal(remove, []string{"gone"}) {
+		t.Fatalf("expected to remove [gone], got %v", remove)
+	}
+}
+
+func TestSave(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.save"
+
+	type config struct {
+		Username string            `plist:"username"`
+		Theme    string            `plist:"theme,omitempty"`
+		Port     int               `plist:"port"`
+		Labels   map[string]string `plist:"labels"`
+		Backup   *string           `plist:"backup"`
+	}
+
+	err := Set(appID, "unrelated", "keep me")
+	testutil.AssertNoError(t, err, "set unrelated key")
+	defer Delete(appID, "unrelated")
+
+	backup := "backup.example.com"
+	cfg := config{
+		Username: "john",
+		Theme:    "dark",
+		Port:     8080,
+		Labels:   map[string]string{"env": "test"},
+		Backup:   &backup,
+	}
+
+	err = Save(appID, &cfg)
+	testutil.AssertNoError(t, err, "save config")
+	defer func() {
+		for _, key := range []string{"username", "theme", "port", "labels", "backup"} {
+			Delete(appID, key)
+		}
+	}()
+
+	var loaded config
+	err = Load(appID, &loaded)
+	testutil.AssertNoError(t, err, "load config")
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Fatalf("expected %+v, got %+v", cfg, loaded)
+	}
+
+	// saving a loaded struct without changes writes nothing
+	ops := recordWrites(t, appID)
+	err = Save(appID, &loaded)
+	testutil.AssertNoError(t, err, "save unchanged config")
+	if len(*ops) != 0 {
+		t.Fatalf("expected no writes for an unchanged config, got %+v", *ops)
+	}
+
+	// empty omitempty fields and nil pointers delete their keys
+	cfg.Theme = ""
+	cfg.Backup = nil
+	cfg.Port = 9090
+
+	err = Save(appID, cfg)
+	testutil.AssertNoError(t, err, "save changes")
+
+	assertKeyExists(t, appID, "theme", false)
+	assertKeyExists(t, appID, "backup", false)
+	assertKeyExists(t, appID, "unrelated", true)
+
+	port, err := Get(appID, "port")
+	testutil.AssertNoError(t, err, "get port")
+	if port != int64(9090) {
+		t.Fatalf("expected 9090, got %v", port)
+	}
+
+	// maps only write their entries
+	err = Save(appID, map[string]any{"username": "jane"})
+	testutil.AssertNoError(t, err, "save map")
+	assertKeyExists(t, appID, "port", true)
+
+	err = Save(appID, []string{"not", "a", "struct"})
+	testutil.AssertError(t, err, "save slice")
+}
@@ -0,0 +1,69 @@
+package schema
+
+import (
+	"github.com/jheddings/go-cfprefs"
+)
+
+// Snapshot reads every preference of a domain into a map of top-level keys
+// to values, as used by ValidateDomain and Infer. Values are read as stored,
+// like cfprefs.GetValue, so data is never interpreted as a property list or
+// JSON.
+func Snapshot(appID string) (map[string]any, error) {
+	keys, err := cfprefs.GetKeys(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(keys))
+	for _, key := range keys {
+		value, err := cfprefs.GetValuePath(appID, cfprefs.Path(key))
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value.Interface()
+	}
+
+	return values, nil
+}
+
+// ValidateDomain checks the stored preferences of a domain against a schema
+// that describes the whole domain, with each top-level key as a property.
+func ValidateDomain(appID string, s *Schema) error {
+	values, err := Snapshot(appID)
+	if err != nil {
+		return err
+	}
+
+	return s.Validate(values)
+}
+
+// ValidateKey checks the stored value at a keypath against the part of a
+// domain schema that describes it. Violations are reported relative to the
+// value, which is read as stored, like Snapshot. Returns an error matching
+// cfprefs.ErrKeyNotFound if the value does not exist.
+func ValidateKey(appID, keypath string, s *Schema) error {
+	kp, err := cfprefs.ParseKeyPathWith(keypath, cfprefs.GetPathSyntax())
+	if err != nil {
+		return err
+	}
+
+	value, err := cfprefs.GetValuePath(appID, kp)
+	if err != nil {
+		return err
+	}
+
+	return s.At(kp).Validate(value.Interface())
+}
+
+// Enforce registers a validator that checks every write to a domain against
+// the schema, returning a function that removes it. Each written value is
+// checked against the part of the schema that describes its keypath, so a
+// write is rejected before it reaches the preferences system.
+//
+// Only writes made by this process through cfprefs are checked, and deleting
+// a value is always allowed, even if the key is required.
+func Enforce(appID string, s *Schema) (remove func()) {
+	return cfprefs.RegisterValidator(appID, func(kp cfprefs.KeyPath, value any) error {
+		return s.At(kp).Validate(value)
+	})
+}
@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs"
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+// appID is a separate domain, so the whole domain can be validated
+const appID = "com.jheddings.cfprefs.testing.schema"
+
+func TestValidateStored(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"properties": {
+			"name": {"type": "string"},
+			"server": {"properties": {"port": {"type": "integer"}}}
+		}
+	}`))
+	testutil.AssertNoError(t, err, "parse schema")
+
+	defer cfprefs.Delete(appID, "name")
+	defer cfprefs.Delete(appID, "server")
+
+	testutil.AssertNoError(t, cfprefs.Set(appID, "name", "app"), "set name")
+	testutil.AssertNoError(t, cfprefs.Set(appID, "server", map[string]any{"port": "80"}), "set server")
+
+	err = ValidateKey(appID, "name", s)
+	testutil.AssertNoError(t, err, "validate valid key")
+
+	err = ValidateKey(appID, "server/port", s)
+	if !errors.Is(err, cfprefs.ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue, got %v", err)
+	}
+
+	err = ValidateKey(appID, "missing", s)
+	if !errors.Is(err, cfprefs.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	var valErr *ValidationErr
+	err = ValidateDomain(appID, s)
+	if !errors.As(err, &valErr) || len(valErr.Violations) != 1 || valErr.Violations[0].Path != "/server/port" {
+		t.Errorf("expected a violation at /server/port, got %v", err)
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"properties": {
+			"server": {
+				"type": "object",
+				"properties": {"port": {"type": "integer", "minimum": 1}},
+				"additionalProperties": false
+			},
+			"ports": {"type": "array", "items": {"type": "integer"}}
+		}
+	}`))
+	testutil.AssertNoError(t, err, "parse schema")
+
+	remove := Enforce(appID, s)
+	defer remove()
+	defer cfprefs.Delete(appID, "server")
+	defer cfprefs.Delete(appID, "ports")
+
+	err = cfprefs.Set(appID, "ports", []any{80})
+	testutil.AssertNoError(t, err, "set valid array")
+
+	err = cfprefs.SetPath(appID, cfprefs.Path("ports", cfprefs.ArrayAppendOp), 443)
+	testutil.AssertNoError(t, err, "append valid element")
+
+	err = cfprefs.Set(appID, "server", map[string]any{"port": 8080})
+	testutil.AssertNoError(t, err, "set valid value")
+
+	err = cfprefs.Set(appID, "server/port", 9090)
+	testutil.AssertNoError(t, err, "set valid nested value")
+
+	testCases := []struct {
+		name    string
+		keypath string
+		value   any
+	}{
+		{name: "Root", keypath: "server", value: map[string]any{"port": 0}},
+		{name: "Nested", keypath: "server/port", value: "80"},
+		{name: "Unknown key", keypath: "server/host", value: "localhost"},
+		{name: "Append", keypath: "ports/" + cfprefs.ArrayAppendOp, value: "https"},
+		{name: "Prepend", keypath: "ports/" + cfprefs.ArrayPrependOp, value: "http"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := cfprefs.Set(appID, tc.keypath, tc.value)
+
+			var valErr *ValidationErr
+			if !errors.Is(err, cfprefs.ErrInvalidValue) || !errors.As(err, &valErr) {
+				t.Fatalf("expected a schema violation, got %v", err)
+			}
+		})
+	}
+
+	port, err := cfprefs.GetInt(appID, "server/port")
+	testutil.AssertNoError(t, err, "get port")
+	if port != 9090 {
+		t.Errorf("expected rejected writes to leave the value unchanged, got %d", port)
+	}
+
+	// writes are allowed again once the validator is removed
+	remove()
+	err = cfprefs.Set(appID, "server/port", "80")
+	testutil.AssertNoError(t, err, "set value without schema")
+}
+
+func TestSnapshotData(t *testing.T) {
+	defer cfprefs.Delete(appID, "blob")
+
+	// data holding JSON is still data, as stored
+	err := cfprefs.Set(appID, "blob", []byte(`{"port": 80}`))
+	testutil.AssertNoError(t, err, "set data")
+
+	snapshot, err := Snapshot(appID)
+	testutil.AssertNoError(t, err, "snapshot")
+
+	if _, ok := snapshot["blob"].([]byte); !ok {
+		t.Fatalf("expected data, got %T", snapshot["blob"])
+	}
+
+	s := Infer(snapshot)
+	if blob := s.Properties["blob"]; blob == nil || len(blob.Type) != 1 || blob.Type[0] != TypeData {
+		t.Errorf("expected blob to be inferred as data, got %+v", blob)
+	}
+
+	err = ValidateKey(appID, "blob", s)
+	testutil.AssertNoError(t, err, "validate data key")
+}
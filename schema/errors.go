@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jheddings/go-cfprefs"
+)
+
+// ErrInvalidSchema is returned when a schema cannot be parsed or uses an
+// unsupported type or pattern.
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// SchemaErr represents a problem with a schema definition
+type SchemaErr struct {
+	Path string // Location of the problem within the schema
+	Msg  string // Additional context
+	Err  error  // Underlying error
+}
+
+// NewSchemaError creates a new SchemaErr at the given location in the schema
+func NewSchemaError(path string) *SchemaErr {
+	return &SchemaErr{Path: path, Err: ErrInvalidSchema}
+}
+
+// WithMsg adds a custom message to the error
+func (e *SchemaErr) WithMsg(msg string) *SchemaErr {
+	e.Msg = msg
+	return e
+}
+
+// WithMsgF adds a formatted custom message to the error
+func (e *SchemaErr) WithMsgF(format string, a ...any) *SchemaErr {
+	e.Msg = fmt.Sprintf(format, a...)
+	return e
+}
+
+// Error returns the error message
+func (e *SchemaErr) Error() string {
+	msg := e.Err.Error()
+	if e.Msg != "" {
+		msg = fmt.Sprintf("%s: %s", e.Msg, msg)
+	}
+	if e.Path != "" {
+		msg = fmt.Sprintf("%s: %s", e.Path, msg)
+	}
+	return msg
+}
+
+// Is implements support for errors.Is
+func (e *SchemaErr) Is(target error) bool {
+	return target == ErrInvalidSchema
+}
+
+// Wrap wraps an error with the SchemaErr
+func (e *SchemaErr) Wrap(err error) *SchemaErr {
+	e.Err = errors.Join(e.Err, err)
+	return e
+}
+
+// Unwrap returns the underlying error
+func (e *SchemaErr) Unwrap() error {
+	return e.Err
+}
+
+// Violation describes a value that does not match its schema.
+type Violation struct {
+	Path string // JSON Pointer of the value, relative to the validated value
+	Msg  string // Description of the problem
+}
+
+// String returns the violation as "path: message".
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Msg
+	}
+	return v.Path + ": " + v.Msg
+}
+
+// ValidationErr lists every violation found while validating a value. It
+// matches cfprefs.ErrInvalidValue with errors.Is.
+type ValidationErr struct {
+	Violations []Violation
+}
+
+// Error returns the error message
+func (e *ValidationErr) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("%s: %s", cfprefs.ErrInvalidValue, strings.Join(msgs, "; "))
+}
+
+// Is implements support for errors.Is
+func (e *ValidationErr) Is(target error) bool {
+	return target == cfprefs.ErrInvalidValue
+}
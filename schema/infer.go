@@ -0,0 +1,156 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jheddings/go-cfprefs"
+)
+
+// Infer returns a schema describing the values observed in one or more
+// domain snapshots, such as those returned by Snapshot. Snapshots of the same
+// domain taken on different machines can be combined to describe all of them.
+//
+// The schema lists every key that was observed, with the types of its values
+// and the width of numbers as their format. A key is required if it appears
+// in every dictionary observed at its location, and optional otherwise. Array
+// items are described by a single schema covering all elements. When a key
+// holds both integers and reals, its type is number.
+//
+// Example usage:
+//
+//	snapshot, err := schema.Snapshot("com.example.app")
+//	s := schema.Infer(snapshot)
+//	data, err := json.MarshalIndent(s, "", "  ")
+func Infer(snapshots ...map[string]any) *Schema {
+	var root shape
+	for _, snapshot := range snapshots {
+		root.observe(snapshot)
+	}
+	return root.schema()
+}
+
+// shape accumulates the values observed at one location.
+type shape struct {
+	types map[Type]bool
+
+	// the widest integer and real observed
+	intWidth  int
+	realWidth int
+
+	// elements of all observed arrays
+	items *shape
+
+	// number of observed dictionaries, and the shape of each key along with
+	// the number of dictionaries that contained it
+	objects int
+	props   map[string]*shape
+	present map[string]int
+}
+
+// observe adds a value to the shape.
+func (s *shape) observe(value any) {
+	if s.types == nil {
+		s.types = make(map[Type]bool)
+	}
+
+	switch v := value.(type) {
+	case string:
+		s.types[TypeString] = true
+	case bool:
+		s.types[TypeBoolean] = true
+	case time.Time:
+		s.types[TypeDate] = true
+	case []byte:
+		s.types[TypeData] = true
+
+	case []any:
+		s.types[TypeArray] = true
+		for _, elem := range v {
+			if s.items == nil {
+				s.items = new(shape)
+			}
+			s.items.observe(elem)
+		}
+
+	case map[string]any:
+		s.types[TypeObject] = true
+		s.objects++
+		if s.props == nil {
+			s.props = make(map[string]*shape)
+			s.present = make(map[string]int)
+		}
+		for key, elem := range v {
+			if s.props[key] == nil {
+				s.props[key] = new(shape)
+			}
+			s.props[key].observe(elem)
+			s.present[key]++
+		}
+
+	default:
+		// the stored width of a number, with unsigned values widened
+		num, err := cfprefs.ValueOf(value)
+
+		// an unsigned integer too large for any signed width is still an
+		// integer, recorded at the widest format
+		if errors.Is(err, cfprefs.ErrOutOfRange) {
+			s.types[TypeInteger] = true
+			s.intWidth = 64
+			return
+		}
+		if err != nil {
+			return
+		}
+		switch num.Kind() {
+		case cfprefs.IntegerKind:
+			s.types[TypeInteger] = true
+			s.intWidth = max(s.intWidth, num.Width())
+		case cfprefs.RealKind:
+			s.types[TypeNumber] = true
+			s.realWidth = max(s.realWidth, num.Width())
+		}
+	}
+}
+
+// schema returns the schema describing all observed values.
+func (s *shape) schema() *Schema {
+	out := &Schema{}
+
+	// integers are also numbers, so only the wider type is listed
+	if s.types[TypeNumber] {
+		delete(s.types, TypeInteger)
+	}
+
+	for _, t := range knownTypes {
+		if s.types[t] {
+			out.Type = append(out.Type, t)
+		}
+	}
+
+	switch {
+	case s.types[TypeNumber]:
+		out.Format = fmt.Sprintf("float%d", s.realWidth)
+	case s.types[TypeInteger]:
+		out.Format = fmt.Sprintf("int%d", s.intWidth)
+	}
+
+	if s.items != nil {
+		out.Items = s.items.schema()
+	}
+
+	if len(s.props) > 0 {
+		out.Properties = make(map[string]*Schema, len(s.props))
+		for _, key := range slices.Sorted(maps.Keys(s.props)) {
+			out.Properties[key] = s.props[key].schema()
+			if s.present[key] == s.objects {
+				out.Required = append(out.Required, key)
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,93 @@
+package schema
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestInfer(t *testing.T) {
+	first := map[string]any{
+		"name":    "app",
+		"port":    int16(8080),
+		"ratio":   int32(1),
+		"updated": time.Now(),
+		"token":   []byte{1, 2},
+		"servers": []any{
+			map[string]any{"host": "a", "port": int32(80)},
+			map[string]any{"host": "b"},
+		},
+		"empty": []any{},
+	}
+
+	second := map[string]any{
+		"name":  "app",
+		"port":  int64(8080),
+		"ratio": float32(0.5),
+		"debug": true,
+		"mixed": []any{"a", int8(1)},
+	}
+
+	s := Infer(first, second)
+
+	encoded, err := json.Marshal(s)
+	testutil.AssertNoError(t, err, "marshal inferred schema")
+
+	expected := `{"type":"object","properties":{` +
+		`"debug":{"type":"boolean"},` +
+		`"empty":{"type":"array"},` +
+		`"mixed":{"type":"array","items":{"type":["string","integer"],"format":"int8"}},` +
+		`"name":{"type":"string"},` +
+		`"port":{"type":"integer","format":"int64"},` +
+		`"ratio":{"type":"number","format":"float32"},` +
+		`"servers":{"type":"array","items":{"type":"object","properties":{` +
+		`"host":{"type":"string"},"port":{"type":"integer","format":"int32"}},"required":["host"]}},` +
+		`"token":{"type":"data"},` +
+		`"updated":{"type":"date"}},` +
+		`"required":["name","port","ratio"]}`
+
+	if string(encoded) != expected {
+		t.Errorf("unexpected schema:\n%s\nexpected:\n%s", encoded, expected)
+	}
+
+	// the observed values are valid for the inferred schema
+	testutil.AssertNoError(t, s.Validate(first), "validate first snapshot")
+	testutil.AssertNoError(t, s.Validate(second), "validate second snapshot")
+
+	if err := s.Validate(map[string]any{"name": "app"}); err == nil {
+		t.Errorf("expected missing required keys to be invalid")
+	}
+}
+
+func TestInferEmpty(t *testing.T) {
+	s := Infer()
+	if len(s.Type) != 0 || s.Properties != nil {
+		t.Errorf("expected an empty schema, got %+v", s)
+	}
+}
+
+func TestInferLargeUnsigned(t *testing.T) {
+	snapshot := map[string]any{
+		"big":   uint64(math.MaxUint64),
+		"small": uint64(1),
+		"mixed": []any{uint8(1), uint(math.MaxUint64)},
+	}
+
+	s := Infer(snapshot)
+
+	encoded, err := json.Marshal(s)
+	testutil.AssertNoError(t, err, "marshal inferred schema")
+
+	expected := `{"type":"object","properties":{` +
+		`"big":{"type":"integer","format":"int64"},` +
+		`"mixed":{"type":"array","items":{"type":"integer","format":"int64"}},` +
+		`"small":{"type":"integer","format":"int64"}},` +
+		`"required":["big","mixed","small"]}`
+
+	if string(encoded) != expected {
+		t.Errorf("unexpected schema:\n%s\nexpected:\n%s", encoded, expected)
+	}
+}
@@ -0,0 +1,249 @@
+// Package schema validates preference values against a subset of JSON Schema
+// (draft 2020-12), extended with the "date" and "data" types of property
+// lists.
+//
+// The supported keywords are type, enum, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minLength, maxLength, pattern, minItems, maxItems, items,
+// properties, patternProperties, additionalProperties and required. Other
+// keywords, such as $schema and $id, are ignored.
+//
+// Example usage:
+//
+//	s, err := schema.Parse(data)
+//	err = schema.ValidateDomain("com.example.app", s)
+//
+//	// reject invalid writes from this process
+//	remove := schema.Enforce("com.example.app", s)
+//	defer remove()
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// Type is the name of a value type in a schema.
+type Type string
+
+const (
+	// TypeString matches string values.
+	TypeString Type = "string"
+
+	// TypeBoolean matches boolean values.
+	TypeBoolean Type = "boolean"
+
+	// TypeInteger matches integers, including reals with an integral value.
+	TypeInteger Type = "integer"
+
+	// TypeNumber matches integers and reals.
+	TypeNumber Type = "number"
+
+	// TypeArray matches arrays.
+	TypeArray Type = "array"
+
+	// TypeObject matches dictionaries.
+	TypeObject Type = "object"
+
+	// TypeDate matches date values.
+	TypeDate Type = "date"
+
+	// TypeData matches raw byte sequences.
+	TypeData Type = "data"
+)
+
+// knownTypes lists the types that may appear in a schema.
+var knownTypes = []Type{TypeString, TypeBoolean, TypeInteger, TypeNumber, TypeArray, TypeObject, TypeDate, TypeData}
+
+// Types is the set of types allowed by a schema. It is written as a single
+// name when it holds one type, and as a list otherwise.
+type Types []Type
+
+// MarshalJSON implements json.Marshaler.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]Type(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var name Type
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = Types{name}
+		return nil
+	}
+
+	var names []Type
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+
+	*t = names
+	return nil
+}
+
+// Schema describes the values allowed for a preference. The zero Schema
+// allows any value.
+type Schema struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Default is the value used when the preference is not set. It is not
+	// used for validation.
+	Default any `json:"default,omitempty"`
+
+	Type Types `json:"type,omitempty"`
+	Enum []any `json:"enum,omitempty"`
+
+	// Format records the storage width of a number, as one of int8, int16,
+	// int32, int64, float32 or float64. It is not used for validation.
+	Format string `json:"format,omitempty"`
+
+	// numbers
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+
+	// strings
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+
+	// arrays
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	// dictionaries
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+
+	// never is set for the schema written as false, which allows no value
+	never bool
+}
+
+// schemaFields has the fields of Schema without its methods.
+type schemaFields Schema
+
+// False returns a schema that allows no value, written as false in JSON. It
+// is typically used as AdditionalProperties to reject unknown keys.
+func False() *Schema {
+	return &Schema{never: true}
+}
+
+// Parse reads a schema from its JSON representation, and checks that its
+// types and patterns are valid.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := decodeJSON(data, &s); err != nil {
+		return nil, NewSchemaError("").Wrap(err).WithMsg("invalid JSON")
+	}
+
+	if err := s.check(""); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	if s.never {
+		return []byte("false"), nil
+	}
+	return json.Marshal(schemaFields(s))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Besides an object, a schema may
+// be written as true, which allows any value, or false, which allows none.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{never: true}
+		return nil
+	}
+
+	var fields schemaFields
+	if err := decodeJSON(data, &fields); err != nil {
+		return err
+	}
+
+	*s = Schema(fields)
+	return nil
+}
+
+// decodeJSON decodes data, keeping numbers in enums and defaults exact.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// check verifies the types and patterns of the schema and its subschemas.
+func (s *Schema) check(path string) error {
+	if s == nil {
+		return nil
+	}
+
+	for _, t := range s.Type {
+		if !slices.Contains(knownTypes, t) {
+			return NewSchemaError(path).WithMsgF("unknown type: %q", t)
+		}
+	}
+
+	if s.Pattern != "" {
+		if _, err := compilePattern(s.Pattern); err != nil {
+			return NewSchemaError(path).Wrap(err).WithMsgF("invalid pattern: %q", s.Pattern)
+		}
+	}
+
+	if err := s.Items.check(path + "/items"); err != nil {
+		return err
+	}
+
+	for name, prop := range s.Properties {
+		if err := prop.check(path + "/properties/" + name); err != nil {
+			return err
+		}
+	}
+
+	for pattern, prop := range s.PatternProperties {
+		if _, err := compilePattern(pattern); err != nil {
+			return NewSchemaError(path).Wrap(err).WithMsgF("invalid property pattern: %q", pattern)
+		}
+		if err := prop.check(path + "/patternProperties/" + pattern); err != nil {
+			return err
+		}
+	}
+
+	return s.AdditionalProperties.check(path + "/additionalProperties")
+}
+
+// patterns caches compiled regular expressions by their source, since a
+// schema is usually used for many values.
+var patterns sync.Map
+
+// compilePattern returns the compiled form of a regular expression.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"port": {"type": "integer", "minimum": 1, "maximum": 65535, "default": 8080},
+			"updated": {"type": ["date", "string"]},
+			"servers": {"type": "array", "items": {"type": "string"}}
+		},
+		"patternProperties": {"^x-": true},
+		"additionalProperties": false,
+		"required": ["port"]
+	}`)
+
+	s, err := Parse(data)
+	testutil.AssertNoError(t, err, "parse schema")
+
+	if len(s.Type) != 1 || s.Type[0] != TypeObject {
+		t.Errorf("expected object type, got %v", s.Type)
+	}
+
+	port := s.Properties["port"]
+	if port == nil || port.Minimum == nil || *port.Minimum != 1 || *port.Maximum != 65535 {
+		t.Fatalf("unexpected port schema: %+v", port)
+	}
+
+	if port.Default != json.Number("8080") {
+		t.Errorf("expected exact default, got %#v", port.Default)
+	}
+
+	if types := s.Properties["updated"].Type; len(types) != 2 || types[0] != TypeDate {
+		t.Errorf("expected a list of types, got %v", types)
+	}
+
+	if s.PatternProperties["^x-"] == nil || s.PatternProperties["^x-"].never {
+		t.Errorf("expected true schema to allow any value")
+	}
+
+	if s.AdditionalProperties == nil || !s.AdditionalProperties.never {
+		t.Errorf("expected false schema to allow no value")
+	}
+
+	// round trip through JSON
+	encoded, err := json.Marshal(s)
+	testutil.AssertNoError(t, err, "marshal schema")
+
+	again, err := Parse(encoded)
+	testutil.AssertNoError(t, err, "parse marshaled schema")
+
+	reencoded, err := json.Marshal(again)
+	testutil.AssertNoError(t, err, "marshal schema again")
+
+	if string(encoded) != string(reencoded) {
+		t.Errorf("expected stable encoding:\n%s\n%s", encoded, reencoded)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Invalid JSON", data: `{"type": `},
+		{name: "Unknown type", data: `{"type": "null"}`},
+		{name: "Nested unknown type", data: `{"properties": {"a": {"items": {"type": "float"}}}}`},
+		{name: "Invalid pattern", data: `{"pattern": "("}`},
+		{name: "Invalid property pattern", data: `{"patternProperties": {"(": {}}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if !errors.Is(err, ErrInvalidSchema) {
+				t.Fatalf("expected ErrInvalidSchema, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/jheddings/go-cfprefs"
+)
+
+// Validate checks a value against the schema, returning a ValidationErr that
+// lists every violation. A nil schema allows any value.
+//
+// Values are expected in the form returned by cfprefs.Get, with arrays as
+// []any and dictionaries as map[string]any.
+func (s *Schema) Validate(value any) error {
+	var v validator
+	v.validate(s, "", value)
+
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	return &ValidationErr{Violations: v.violations}
+}
+
+// At returns the schema for the value at a keypath, where s describes the
+// whole domain. It returns nil if the schema does not constrain the value.
+//
+// For a dictionary, a key listed in properties is used first, then the first
+// matching patternProperties entry in order of the patterns, and finally
+// additionalProperties. Array indexes, and the cfprefs.ArrayAppendOp and
+// cfprefs.ArrayPrependOp tokens, use items.
+func (s *Schema) At(kp cfprefs.KeyPath) *Schema {
+	tokens := kp.Tokens()
+	if kp.Key() != "" {
+		tokens = append([]string{kp.Key()}, tokens...)
+	}
+
+	for _, token := range tokens {
+		if s == nil || s.never {
+			break
+		}
+		s = s.child(token)
+	}
+
+	return s
+}
+
+// child returns the schema for a single token below s.
+func (s *Schema) child(token string) *Schema {
+	if prop, ok := s.Properties[token]; ok {
+		return prop
+	}
+
+	for _, pattern := range slices.Sorted(maps.Keys(s.PatternProperties)) {
+		if re, err := compilePattern(pattern); err == nil && re.MatchString(token) {
+			return s.PatternProperties[pattern]
+		}
+	}
+
+	if s.Items != nil && isIndex(token) {
+		return s.Items
+	}
+
+	return s.AdditionalProperties
+}
+
+// isIndex reports whether a token refers to an array element.
+func isIndex(token string) bool {
+	switch token {
+	case "-", cfprefs.ArrayAppendOp, cfprefs.ArrayPrependOp:
+		return true
+	}
+	_, err := strconv.Atoi(token)
+	return err == nil
+}
+
+// validator collects the violations found while validating a value.
+type validator struct {
+	violations []Violation
+}
+
+// fail records a violation at the given path.
+func (v *validator) fail(path, format string, a ...any) {
+	v.violations = append(v.violations, Violation{Path: path, Msg: fmt.Sprintf(format, a...)})
+}
+
+// validate checks a value and its children against a schema.
+func (v *validator) validate(s *Schema, path string, value any) {
+	if s == nil {
+		return
+	}
+
+	if s.never {
+		v.fail(path, "no value is allowed")
+		return
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t Type) bool { return matchesType(t, value) }) {
+		v.fail(path, "expected %s, found %s", typeNames(s.Type), typeOf(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, value) }) {
+		v.fail(path, "value %v is not one of %v", value, s.Enum)
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateString(s, path, value)
+	case []any:
+		v.validateArray(s, path, value)
+	case map[string]any:
+		v.validateObject(s, path, value)
+	default:
+		if n, ok := number(value); ok {
+			v.validateNumber(s, path, n)
+		}
+	}
+}
+
+// validateNumber checks the range keywords.
+func (v *validator) validateNumber(s *Schema, path string, n *big.Rat) {
+	check := func(limit *float64, ok func(cmp int) bool, desc string) {
+		if limit == nil {
+			return
+		}
+		bound, exact := number(*limit)
+		if exact && !ok(n.Cmp(bound)) {
+			v.fail(path, "value %s is %s %v", n.RatString(), desc, *limit)
+		}
+	}
+
+	check(s.Minimum, func(cmp int) bool { return cmp >= 0 }, "less than")
+	check(s.Maximum, func(cmp int) bool { return cmp <= 0 }, "greater than")
+	check(s.ExclusiveMinimum, func(cmp int) bool { return cmp > 0 }, "not greater than")
+	check(s.ExclusiveMaximum, func(cmp int) bool { return cmp < 0 }, "not less than")
+}
+
+// validateString checks the length and pattern keywords.
+func (v *validator) validateString(s *Schema, path string, str string) {
+	length := utf8.RuneCountInString(str)
+
+	if s.MinLength != nil && length < *s.MinLength {
+		v.fail(path, "length %d is less than %d", length, *s.MinLength)
+	}
+
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.fail(path, "length %d is greater than %d", length, *s.MaxLength)
+	}
+
+	if s.Pattern != "" {
+		re, err := compilePattern(s.Pattern)
+		if err != nil {
+			v.fail(path, "invalid pattern: %q", s.Pattern)
+		} else if !re.MatchString(str) {
+			v.fail(path, "value %q does not match %q", str, s.Pattern)
+		}
+	}
+}
+
+// validateArray checks the size keywords and each element.
+func (v *validator) validateArray(s *Schema, path string, arr []any) {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		v.fail(path, "array has %d items, fewer than %d", len(arr), *s.MinItems)
+	}
+
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		v.fail(path, "array has %d items, more than %d", len(arr), *s.MaxItems)
+	}
+
+	for idx, elem := range arr {
+		v.validate(s.Items, path+"/"+strconv.Itoa(idx), elem)
+	}
+}
+
+// validateObject checks the required keys and each value.
+func (v *validator) validateObject(s *Schema, path string, obj map[string]any) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(path, "missing required key %q", name)
+		}
+	}
+
+	patterns := slices.Sorted(maps.Keys(s.PatternProperties))
+
+	for _, key := range slices.Sorted(maps.Keys(obj)) {
+		child := path + "/" + jsonpointer.Escape(key)
+		matched := false
+
+		if prop, ok := s.Properties[key]; ok {
+			matched = true
+			v.validate(prop, child, obj[key])
+		}
+
+		for _, pattern := range patterns {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				v.fail(path, "invalid property pattern: %q", pattern)
+				continue
+			}
+			if re.MatchString(key) {
+				matched = true
+				v.validate(s.PatternProperties[pattern], child, obj[key])
+			}
+		}
+
+		if !matched {
+			v.validate(s.AdditionalProperties, child, obj[key])
+		}
+	}
+}
+
+// matchesType reports whether a value is of the given schema type.
+func matchesType(t Type, value any) bool {
+	switch t {
+	case TypeString:
+		_, ok := value.(string)
+		return ok
+	case TypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case TypeInteger:
+		n, ok := number(value)
+		return ok && n.IsInt()
+	case TypeNumber:
+		_, ok := number(value)
+		return ok
+	case TypeArray:
+		_, ok := value.([]any)
+		return ok
+	case TypeObject:
+		_, ok := value.(map[string]any)
+		return ok
+	case TypeDate:
+		_, ok := value.(time.Time)
+		return ok
+	case TypeData:
+		_, ok := value.([]byte)
+		return ok
+	}
+	return false
+}
+
+// typeOf returns the name of the schema type of a value.
+func typeOf(value any) string {
+	for _, t := range knownTypes {
+		// integers also match number, so the narrower type is listed first
+		if matchesType(t, value) {
+			return string(t)
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// typeNames returns a readable list of types.
+func typeNames(types Types) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, " or ")
+}
+
+// number returns the exact value of a number, or false if the value is not a
+// finite number.
+func number(value any) (*big.Rat, bool) {
+	rv := reflect.ValueOf(value)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetUint64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		n := new(big.Rat).SetFloat64(rv.Float())
+		return n, n != nil
+	}
+
+	if num, ok := value.(json.Number); ok {
+		return new(big.Rat).SetString(num.String())
+	}
+
+	return nil, false
+}
+
+// equal reports whether a value matches an enum entry. Numbers are compared
+// by value, and dates may be listed as RFC 3339 strings.
+func equal(want, value any) bool {
+	if a, ok := number(want); ok {
+		b, ok := number(value)
+		return ok && a.Cmp(b) == 0
+	}
+
+	switch value := value.(type) {
+	case time.Time:
+		switch want := want.(type) {
+		case time.Time:
+			return want.Equal(value)
+		case string:
+			date, err := time.Parse(time.RFC3339, want)
+			return err == nil && date.Equal(value)
+		}
+		return false
+
+	case []byte:
+		want, ok := want.([]byte)
+		return ok && bytes.Equal(want, value)
+
+	case []any:
+		want, ok := want.([]any)
+		return ok && slices.EqualFunc(want, value, equal)
+
+	case map[string]any:
+		want, ok := want.(map[string]any)
+		return ok && maps.EqualFunc(want, value, equal)
+	}
+
+	return reflect.DeepEqual(want, value)
+}
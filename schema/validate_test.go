@@ -0,0 +1,172 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs"
+)
+
+// testSchema describes a small application domain
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+		"port": {"type": "integer", "minimum": 1, "exclusiveMaximum": 65536},
+		"ratio": {"type": "number", "exclusiveMinimum": 0, "maximum": 1},
+		"level": {"enum": ["debug", "info", 3]},
+		"updated": {"type": "date"},
+		"token": {"type": "data"},
+		"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2},
+		"server": {
+			"type": "object",
+			"properties": {"host": {"type": "string"}},
+			"required": ["host"]
+		}
+	},
+	"patternProperties": {"^x-": {"type": "boolean"}},
+	"additionalProperties": false
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		value      any
+		violations []string
+	}{
+		{
+			name: "Valid",
+			value: map[string]any{
+				"name":    "app",
+				"port":    int64(8080),
+				"ratio":   0.5,
+				"level":   int32(3),
+				"updated": time.Now(),
+				"token":   []byte{1, 2},
+				"tags":    []any{"a"},
+				"server":  map[string]any{"host": "localhost"},
+				"x-debug": true,
+			},
+		},
+		{
+			name:       "Integral real",
+			value:      map[string]any{"port": float64(80)},
+			violations: nil,
+		},
+		{
+			name:       "Wrong type",
+			value:      map[string]any{"port": "80", "updated": "2024-01-01", "token": "abc"},
+			violations: []string{"/port: expected integer, found string", "/token: expected data, found string", "/updated: expected date, found string"},
+		},
+		{
+			name:       "Fractional integer",
+			value:      map[string]any{"port": 80.5},
+			violations: []string{"/port: expected integer, found number"},
+		},
+		{
+			name:       "Range",
+			value:      map[string]any{"port": int64(65536), "ratio": 0},
+			violations: []string{"/port: value 65536 is not less than 65536", "/ratio: value 0 is not greater than 0"},
+		},
+		{
+			name:       "String",
+			value:      map[string]any{"name": "Application"},
+			violations: []string{"/name: length 11 is greater than 8", `/name: value "Application" does not match "^[a-z]+$"`},
+		},
+		{
+			name:       "Enum",
+			value:      map[string]any{"level": "trace"},
+			violations: []string{"/level: value trace is not one of [debug info 3]"},
+		},
+		{
+			name:       "Array",
+			value:      map[string]any{"tags": []any{"a", 2, "c"}},
+			violations: []string{"/tags: array has 3 items, more than 2", "/tags/1: expected string, found integer"},
+		},
+		{
+			name:       "Required",
+			value:      map[string]any{"server": map[string]any{}},
+			violations: []string{`/server: missing required key "host"`},
+		},
+		{
+			name:       "Pattern properties",
+			value:      map[string]any{"x-debug": "yes", "extra/key": 1},
+			violations: []string{"/extra~1key: no value is allowed", "/x-debug: expected boolean, found string"},
+		},
+		{
+			name:       "Root type",
+			value:      "value",
+			violations: []string{"expected object, found string"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := s.Validate(tc.value)
+
+			if len(tc.violations) == 0 {
+				if err != nil {
+					t.Fatalf("expected value to be valid, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, cfprefs.ErrInvalidValue) {
+				t.Fatalf("expected ErrInvalidValue, got %v", err)
+			}
+
+			var valErr *ValidationErr
+			if !errors.As(err, &valErr) {
+				t.Fatalf("expected *ValidationErr, got %T", err)
+			}
+
+			var violations []string
+			for _, v := range valErr.Violations {
+				violations = append(violations, v.String())
+			}
+
+			if !reflect.DeepEqual(violations, tc.violations) {
+				t.Errorf("expected violations %q, got %q", tc.violations, violations)
+			}
+		})
+	}
+}
+
+func TestSchemaAt(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		kp       cfprefs.KeyPath
+		expected *Schema
+	}{
+		{name: "Domain", kp: cfprefs.KeyPath{}, expected: s},
+		{name: "Property", kp: cfprefs.Path("server"), expected: s.Properties["server"]},
+		{name: "Nested", kp: cfprefs.Path("server", "host"), expected: s.Properties["server"].Properties["host"]},
+		{name: "Item", kp: cfprefs.Path("tags", 3), expected: s.Properties["tags"].Items},
+		{name: "Append", kp: cfprefs.Path("tags", "-"), expected: s.Properties["tags"].Items},
+		{name: "Append op", kp: cfprefs.Path("tags", cfprefs.ArrayAppendOp), expected: s.Properties["tags"].Items},
+		{name: "Prepend op", kp: cfprefs.Path("tags", cfprefs.ArrayPrependOp), expected: s.Properties["tags"].Items},
+		{name: "Pattern", kp: cfprefs.Path("x-trace"), expected: s.PatternProperties["^x-"]},
+		{name: "Additional", kp: cfprefs.Path("other", "value"), expected: s.AdditionalProperties},
+		{name: "Unconstrained", kp: cfprefs.Path("server", "port"), expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := s.At(tc.kp); actual != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
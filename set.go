@@ -1,9 +1,11 @@
 package cfprefs
 
 import (
+	"context"
+	"maps"
+	"slices"
 	"strconv"
 
-	"github.com/go-openapi/jsonpointer"
 	"github.com/jheddings/go-cfprefs/internal"
 )
 
@@ -31,106 +33,225 @@ const (
 //
 //	// Set a nested value
 //	err := Set("com.example.app", "config/server/port", 8080)
-func Set(appID, keypath string, value any) error {
+//
+// A nil value deletes the value at the keypath. Nil values inside arrays and
+// dictionaries are handled according to the NilPolicy.
+func Set(appID, keypath string, value any) (err error) {
+	defer wrapError(&err, "set", appID, keypath)
+
+	kp, err := parseKeypath(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return SetPath(appID, kp, value)
+}
+
+// SetContext writes a preference value like Set, honoring cancellation of ctx
+// before reading the current value, while converting it and before writing.
+//
+// Returns the context error if ctx is done before the value is written.
+func SetContext(ctx context.Context, appID, keypath string, value any) (err error) {
+	defer wrapError(&err, "set", appID, keypath)
+
 	kp, err := parseKeypath(keypath)
 	if err != nil {
 		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
 	}
 
+	return setPath(ctx, appID, kp, value)
+}
+
+// SetPath writes a preference value for the given KeyPath and application ID.
+//
+// Example usage:
+//
+//	err := SetPath("com.example.app", Path("config", "servers", 0, "port"), 8080)
+func SetPath(appID string, kp KeyPath, value any) (err error) {
+	defer wrapError(&err, "set", appID, kp.String())
+
+	return setPath(context.Background(), appID, kp, value)
+}
+
+// setPath writes the value for a KeyPath, honoring cancellation of ctx.
+func setPath(ctx context.Context, appID string, kp KeyPath, value any) error {
+	value, err := prepareValue(appID, kp, value)
+	if err != nil {
+		return err
+	}
+
+	// a nil value cannot be stored, so it removes the value instead
+	if value == nil {
+		return deletePath(ctx, appID, kp)
+	}
+
+	op := WriteOp{AppID: appID, KeyPath: kp, New: value}
+	read := func() (any, bool, error) { return readRoot(ctx, appID, kp.Key()) }
+
+	return runWrite(ctx, op, read, func() error {
+		return storeValue(ctx, appID, kp, value)
+	})
+}
+
+// storeValue writes a prepared value for a KeyPath, honoring cancellation of
+// ctx.
+func storeValue(ctx context.Context, appID string, kp KeyPath, value any) error {
 	// if there is no pointer, just set the value
 	if kp.IsRoot() {
-		return internal.Set(appID, kp.Key, value)
+		return writeRoot(ctx, appID, kp.Key(), value)
 	}
 
 	// get or create the root value
-	var root any
-	exists, err := internal.Exists(appID, kp.Key)
+	root, exists, err := readRoot(ctx, appID, kp.Key())
 	if err != nil {
-		return NewInternalError().Wrap(err).WithMsgF("failed to check: %s", kp.Key)
+		return err
 	}
 
-	if exists {
-		root, err = internal.Get(appID, kp.Key)
-		if err != nil {
-			return NewInternalError().Wrap(err).WithMsgF("failed to get: %s", kp.Key)
-		}
-	} else {
+	if !exists {
 		root = make(map[string]any)
 	}
 
-	ptr, err := jsonpointer.New(kp.Path)
-	if err != nil {
-		return NewKeyPathError().Wrap(err).WithMsgF("invalid path: %s", kp.Path)
-	}
-
 	// set the value at the specified path
-	modified, err := setValueAtPath(root, ptr.DecodedTokens(), value)
+	modified, err := setValueAtPath(root, kp.tokens, value)
 	if err != nil {
-		return NewInternalError().Wrap(err).WithMsgF("failed to set: %s", kp.Path)
+		return NewInternalError().Wrap(err).WithMsgF("failed to set: %s", kp.Pointer())
 	}
 
 	// write the modified root value back
-	return internal.Set(appID, kp.Key, modified)
+	return writeRoot(ctx, appID, kp.Key(), modified)
+}
+
+// writeRoot writes the value of a top-level key, returning the context error
+// if ctx is done before or while writing.
+func writeRoot(ctx context.Context, appID, key string, value any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := internal.SetContext(ctx, appID, key, value); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	return nil
+}
+
+// prepareValue converts a value into the plain types understood by the
+// preferences system and checks that it can be stored at the given keypath
+// without loss. See prepareNode for the supported types. The prepared value
+// is checked by any validators registered for the domain.
+//
+// The value is not modified. If any element is replaced, the containers along
+// its path are copied and the copy is returned.
+func prepareValue(appID string, kp KeyPath, value any) (any, error) {
+	prepared, _, err := prepareNode(appID, kp, value, currentWritePolicy())
+	if err != nil || prepared == nil {
+		return prepared, err
+	}
+
+	if err := validateWrite(appID, kp, prepared); err != nil {
+		return nil, err
+	}
+
+	return prepared, nil
+}
+
+// writePolicy holds the settings that control how values are prepared.
+type writePolicy struct {
+	overflow OverflowPolicy
+	nils     NilPolicy
+
+	// visiting holds the pointers being converted, to detect cycles
+	visiting map[visit]bool
+}
+
+// currentWritePolicy returns the global settings for preparing values.
+func currentWritePolicy() writePolicy {
+	return writePolicy{overflow: GetOverflowPolicy(), nils: GetNilPolicy()}
 }
 
 // setValueAtPath uses a pointer walker to set a value at the specified path.
+//
+// The root is not modified. Instead, a new root is returned that shares all
+// unchanged subtrees with the original and copies only the containers along
+// the modified path.
 func setValueAtPath(root any, tokens []string, value any) (any, error) {
+	return updateValueAtPath(root, tokens, value, true)
+}
+
+// setValueInPlace sets a value at the specified path, modifying the containers
+// along the path directly. It must only be used on values owned by the caller.
+func setValueInPlace(root any, tokens []string, value any) (any, error) {
+	return updateValueAtPath(root, tokens, value, false)
+}
+
+// updateValueAtPath sets a value at the specified path, optionally copying the
+// containers along the path rather than modifying them.
+func updateValueAtPath(root any, tokens []string, value any, copyOnWrite bool) (any, error) {
 	var walker *pointerWalker
 
 	handler := pathTokenHandler{
 		onArrayIndex: func(arr []any, index int, remaining []string) (any, error) {
-			// if this is the last token, set the value at the index
-			if len(remaining) == 0 {
-				arr[index] = value
-				return arr, nil
-			}
+			data := value
 
-			// continue to walk the path
-			data, err := walker.walk(arr[index], remaining)
-			if err != nil {
-				return nil, err
+			// continue to walk the path if this is not the last token
+			if len(remaining) > 0 {
+				var err error
+				data, err = walker.walk(arr[index], remaining)
+				if err != nil {
+					return nil, err
+				}
 			}
 
 			// update the array with any modifications
+			if copyOnWrite {
+				arr = slices.Clone(arr)
+			}
 			arr[index] = data
 			return arr, nil
 		},
 		onArrayAppend: func(arr []any, remaining []string) (any, error) {
-			// if this is the last token, append the value to the array
-			if len(remaining) == 0 {
-				return append(arr, value), nil
-			}
+			data := value
 
-			// construct the remaining path elements
-			new := createStructureFor(remaining[0])
-			data, err := walker.walk(new, remaining)
-			if err != nil {
-				return nil, err
+			// construct the remaining path elements if this is not the last token
+			if len(remaining) > 0 {
+				var err error
+				data, err = walker.walk(createStructureFor(remaining[0]), remaining)
+				if err != nil {
+					return nil, err
+				}
 			}
 
-			// update the array with any modifications
+			// clipping forces append to allocate a new backing array
+			if copyOnWrite {
+				arr = slices.Clip(arr)
+			}
 			return append(arr, data), nil
 		},
 		onObjectKey: func(obj map[string]any, key string, remaining []string) (any, error) {
-			// if this is the last token, set the value at the key
-			if len(remaining) == 0 {
-				obj[key] = value
-				return obj, nil
-			}
+			data := value
 
-			// get or create the child
-			child, exists := obj[key]
-			if !exists {
-				child = createStructureFor(remaining[0])
-			}
+			// continue to walk the path if this is not the last token
+			if len(remaining) > 0 {
+				// get or create the child
+				child, exists := obj[key]
+				if !exists {
+					child = createStructureFor(remaining[0])
+				}
 
-			// construct the remaining path elements
-			data, err := walker.walk(child, remaining)
-			if err != nil {
-				return nil, err
+				var err error
+				data, err = walker.walk(child, remaining)
+				if err != nil {
+					return nil, err
+				}
 			}
 
 			// update the object with any modifications
+			if copyOnWrite {
+				obj = maps.Clone(obj)
+			}
 			obj[key] = data
 			return obj, nil
 		},
@@ -140,7 +261,7 @@ func setValueAtPath(root any, tokens []string, value any) (any, error) {
 	}
 
 	walker = newPointerWalker(&handler)
-	return walker.walk(root, tokens)
+	return walker.walkPath(root, tokens)
 }
 
 // createStructureFor creates an empty array or map based on the next token.
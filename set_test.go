@@ -1,6 +1,8 @@
 package cfprefs
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -218,3 +220,26 @@ func TestSetNonObjectSegment(t *testing.T) {
 	err := Set(appID, "simple-string/nested/value", "should fail")
 	testutil.AssertError(t, err, "setting through non-object segment")
 }
+
+func TestSetContext(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	err := SetContext(context.Background(), appID, "ctx-set-test/name", "value")
+	testutil.AssertNoError(t, err, "set with context")
+	defer Delete(appID, "ctx-set-test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = SetContext(ctx, appID, "ctx-set-test/name", "canceled")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// the canceled write must not have changed the stored value
+	value, err := Get(appID, "ctx-set-test/name")
+	testutil.AssertNoError(t, err, "get unchanged value")
+	if value != "value" {
+		t.Fatalf("expected 'value', got %v", value)
+	}
+}
@@ -0,0 +1,286 @@
+package cfprefs
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// PathSyntax identifies a keypath notation.
+type PathSyntax int32
+
+const (
+	// PointerSyntax is the default keypath notation: a top-level key followed
+	// by a JSON Pointer path (e.g., "config/servers/0/host").
+	PointerSyntax PathSyntax = iota
+
+	// DotSyntax is a dot and bracket keypath notation (e.g.,
+	// "config.servers[0].host"). Keys containing special characters may be
+	// quoted (e.g., `config."my.key"` or `config["my.key"]`), and an empty
+	// bracket (e.g., "items[]") appends to an array.
+	DotSyntax
+)
+
+// defaultPathSyntax is the notation used to parse string keypaths.
+var defaultPathSyntax atomic.Int32
+
+// SetPathSyntax sets the notation used to parse string keypaths passed to
+// Get, Set, Delete, Exists and the typed accessors. The default is
+// PointerSyntax.
+func SetPathSyntax(syntax PathSyntax) {
+	defaultPathSyntax.Store(int32(syntax))
+}
+
+// GetPathSyntax returns the notation used to parse string keypaths.
+func GetPathSyntax() PathSyntax {
+	return PathSyntax(defaultPathSyntax.Load())
+}
+
+// String returns the name of the path syntax.
+func (s PathSyntax) String() string {
+	switch s {
+	case PointerSyntax:
+		return "pointer"
+	case DotSyntax:
+		return "dot"
+	}
+	return "unknown"
+}
+
+// ParseKeyPathWith parses a keypath string using the given notation.
+//
+// Example usage:
+//
+//	kp, err := ParseKeyPathWith("config.servers[0].host", DotSyntax)
+//	value, err := GetPath("com.example.app", kp)
+func ParseKeyPathWith(keypath string, syntax PathSyntax) (KeyPath, error) {
+	switch syntax {
+	case PointerSyntax:
+		return ParseKeyPath(keypath)
+	case DotSyntax:
+		return ParseDotPath(keypath)
+	}
+
+	return KeyPath{}, NewKeyPathError().WithMsgF("unsupported path syntax: %d", syntax)
+}
+
+// StringWith returns the keypath as a string in the given notation, which
+// parses back into the same KeyPath with ParseKeyPathWith. Keys are quoted in
+// dot notation only when they contain special characters, and an unsupported
+// notation falls back to pointer syntax.
+//
+// Example usage:
+//
+//	kp := Path("config", "servers", 0, "host")
+//	s := kp.StringWith(DotSyntax) // "config.servers[0].host"
+func (k KeyPath) StringWith(syntax PathSyntax) string {
+	if syntax != DotSyntax {
+		return k.String()
+	}
+
+	var sb strings.Builder
+	writeDotName(&sb, k.key)
+
+	for _, token := range k.tokens {
+		switch {
+		case token == ArrayAppendOp:
+			sb.WriteString("[]")
+		case isIndexToken(token):
+			sb.WriteString("[" + token + "]")
+		default:
+			sb.WriteByte('.')
+			writeDotName(&sb, token)
+		}
+	}
+
+	return sb.String()
+}
+
+// writeDotName writes an object key in dot notation, quoting it if it would
+// not otherwise parse back as a bare key.
+func writeDotName(sb *strings.Builder, name string) {
+	if name != "" && !strings.ContainsAny(name, ".[") && name[0] != '"' && name[0] != '\'' {
+		sb.WriteString(name)
+		return
+	}
+
+	sb.WriteByte('"')
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c == '"' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(name[i])
+	}
+	sb.WriteByte('"')
+}
+
+// isIndexToken returns true if the token is written as an array index.
+func isIndexToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseKeypath parses a keypath string using the current default notation.
+func parseKeypath(keypath string) (KeyPath, error) {
+	return ParseKeyPathWith(keypath, GetPathSyntax())
+}
+
+// ParseDotPath parses a keypath in dot and bracket notation into a KeyPath.
+//
+// Object keys are separated by "." and array indices are given in brackets
+// (e.g., "config.servers[0].host"). Keys may be quoted with double or single
+// quotes, either as a segment or inside brackets, and quoted keys support
+// backslash escapes for the quote character and backslash.
+func ParseDotPath(keypath string) (KeyPath, error) {
+	if keypath == "" {
+		return KeyPath{}, NewKeyPathError().WithMsg("invalid keypath; empty string")
+	}
+
+	p := &dotParser{input: keypath}
+
+	key, err := p.parseName()
+	if err != nil {
+		return KeyPath{}, err
+	}
+
+	var tokens []string
+	for !p.done() {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			name, err := p.parseName()
+			if err != nil {
+				return KeyPath{}, err
+			}
+			tokens = append(tokens, name)
+
+		case '[':
+			p.pos++
+			token, err := p.parseBracket()
+			if err != nil {
+				return KeyPath{}, err
+			}
+			tokens = append(tokens, token)
+
+		default:
+			return KeyPath{}, p.errorf("unexpected character %q", p.peek())
+		}
+	}
+
+	return KeyPath{key: key, tokens: tokens}, nil
+}
+
+// dotParser holds the state for parsing a dot notation keypath.
+type dotParser struct {
+	input string
+	pos   int
+}
+
+// done returns true if the entire input has been consumed.
+func (p *dotParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+// peek returns the current character without consuming it.
+func (p *dotParser) peek() byte {
+	return p.input[p.pos]
+}
+
+// errorf creates a KeyPathErr describing a failure at the current position.
+func (p *dotParser) errorf(format string, a ...any) *KeyPathErr {
+	return NewKeyPathError().WithMsgF("invalid keypath; %s at offset %d: %s", fmt.Sprintf(format, a...), p.pos, p.input)
+}
+
+// parseName parses a quoted or bare object key.
+func (p *dotParser) parseName() (string, error) {
+	if p.done() {
+		return "", p.errorf("expected key")
+	}
+
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.parseQuoted()
+	}
+
+	start := p.pos
+	for !p.done() && p.peek() != '.' && p.peek() != '[' {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", p.errorf("empty key")
+	}
+
+	return p.input[start:p.pos], nil
+}
+
+// parseQuoted parses a quoted string, including the surrounding quotes.
+func (p *dotParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	var sb strings.Builder
+	for !p.done() {
+		c := p.peek()
+		p.pos++
+
+		switch c {
+		case quote:
+			return sb.String(), nil
+		case '\\':
+			if p.done() {
+				return "", p.errorf("unterminated escape")
+			}
+			sb.WriteByte(p.peek())
+			p.pos++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated quote")
+}
+
+// parseBracket parses the contents of a bracket after the opening "[".
+func (p *dotParser) parseBracket() (string, error) {
+	if p.done() {
+		return "", p.errorf("unterminated bracket")
+	}
+
+	var token string
+
+	switch c := p.peek(); {
+	case c == ']':
+		token = ArrayAppendOp
+
+	case c == '"' || c == '\'':
+		name, err := p.parseQuoted()
+		if err != nil {
+			return "", err
+		}
+		token = name
+
+	default:
+		start := p.pos
+		for !p.done() && p.peek() >= '0' && p.peek() <= '9' {
+			p.pos++
+		}
+		if p.pos == start {
+			return "", p.errorf("expected array index or quoted key")
+		}
+		token = p.input[start:p.pos]
+	}
+
+	if p.done() || p.peek() != ']' {
+		return "", p.errorf("expected ']'")
+	}
+	p.pos++
+
+	return token, nil
+}
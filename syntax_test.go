@@ -0,0 +1,127 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestParseDotPath(t *testing.T) {
+	testCases := []struct {
+		keypath string
+		key     string
+		tokens  []string
+	}{
+		{keypath: "settings", key: "settings"},
+		{keypath: "config.server.port", key: "config", tokens: []string{"server", "port"}},
+		{keypath: "items[0].name", key: "items", tokens: []string{"0", "name"}},
+		{keypath: "matrix[1][2]", key: "matrix", tokens: []string{"1", "2"}},
+		{keypath: "items[]", key: "items", tokens: []string{ArrayAppendOp}},
+		{keypath: `config."my.key".value`, key: "config", tokens: []string{"my.key", "value"}},
+		{keypath: `config["my.key"]`, key: "config", tokens: []string{"my.key"}},
+		{keypath: `config['a/b']`, key: "config", tokens: []string{"a/b"}},
+		{keypath: `"my.pref".enabled`, key: "my.pref", tokens: []string{"enabled"}},
+		{keypath: `config."say \"hi\""`, key: "config", tokens: []string{`say "hi"`}},
+		{keypath: "paths.a/b", key: "paths", tokens: []string{"a/b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.keypath, func(t *testing.T) {
+			kp, err := ParseDotPath(tc.keypath)
+			testutil.AssertNoError(t, err, "parse dot path")
+
+			if kp.Key() != tc.key {
+				t.Fatalf("expected key %q, got %q", tc.key, kp.Key())
+			}
+			if !reflect.DeepEqual(kp.tokens, tc.tokens) {
+				t.Fatalf("expected tokens %v, got %v", tc.tokens, kp.tokens)
+			}
+		})
+	}
+}
+
+func TestKeyPathStringWith(t *testing.T) {
+	testCases := []struct {
+		kp      KeyPath
+		pointer string
+		dot     string
+	}{
+		{kp: Path("settings"), pointer: "settings", dot: "settings"},
+		{kp: Path("config", "servers", 0, "host"), pointer: "config/servers/0/host", dot: "config.servers[0].host"},
+		{kp: Path("items", ArrayAppendOp), pointer: "items/~0]", dot: "items[]"},
+		{kp: Path("my.pref", "a/b"), pointer: "my.pref/a~1b", dot: `"my.pref".a/b`},
+		{kp: Path("config", `say "hi"`, "", "[x]"), pointer: `config/say "hi"//[x]`, dot: `config.say "hi".""."[x]"`},
+		{kp: Path(`"quoted"`, `a\b`), pointer: `"quoted"/a\b`, dot: `"\"quoted\"".a\b`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pointer, func(t *testing.T) {
+			for syntax, expected := range map[PathSyntax]string{PointerSyntax: tc.pointer, DotSyntax: tc.dot} {
+				actual := tc.kp.StringWith(syntax)
+				if actual != expected {
+					t.Fatalf("expected %s keypath %q, got %q", syntax, expected, actual)
+				}
+
+				// the string parses back into the same keypath
+				kp, err := ParseKeyPathWith(actual, syntax)
+				testutil.AssertNoError(t, err, "parse "+actual)
+				if kp.Key() != tc.kp.Key() || !slices.Equal(kp.Tokens(), tc.kp.Tokens()) {
+					t.Fatalf("expected %v to round trip, got %v", tc.kp, kp)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDotPathErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		".config",
+		"config.",
+		"config..port",
+		"items[a]",
+		"items[0",
+		"items[0]x",
+		`config."unterminated`,
+		"[0]",
+	}
+
+	for _, keypath := range invalid {
+		t.Run(keypath, func(t *testing.T) {
+			_, err := ParseDotPath(keypath)
+			if !errors.Is(err, ErrInvalidKeyPath) {
+				t.Fatalf("expected ErrInvalidKeyPath, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDotSyntaxOperations(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	SetPathSyntax(DotSyntax)
+	defer SetPathSyntax(PointerSyntax)
+
+	err := Set(appID, "dot-test.servers[].host", "localhost")
+	testutil.AssertNoError(t, err, "set with dot syntax")
+	defer Delete(appID, "dot-test")
+
+	value, err := GetStr(appID, "dot-test.servers[0].host")
+	testutil.AssertNoError(t, err, "get with dot syntax")
+	if value != "localhost" {
+		t.Fatalf("expected 'localhost', got '%s'", value)
+	}
+
+	// the same keypath may be selected explicitly per call
+	kp, err := ParseKeyPathWith("dot-test/servers/0/host", PointerSyntax)
+	testutil.AssertNoError(t, err, "parse pointer syntax")
+
+	exists, err := ExistsPath(appID, kp)
+	testutil.AssertNoError(t, err, "check pointer path")
+	if !exists {
+		t.Fatal("expected pointer path to exist")
+	}
+}
@@ -0,0 +1,301 @@
+package cfprefs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jheddings/go-cfprefs/internal"
+)
+
+// Tx stages changes to the preferences of a single application ID and writes
+// them together with a single synchronize when committed.
+//
+// Nested writes to the same top-level key are merged in memory, so each key
+// is read at most once and written at most once per transaction. A Tx is safe
+// for concurrent use.
+type Tx struct {
+	appID string
+
+	mu      sync.Mutex
+	base    map[string]any
+	staged  map[string]any
+	removed map[string]bool
+	ops     []WriteOp
+	closed  bool
+}
+
+// Begin starts a new transaction for the given appID.
+//
+// Example usage:
+//
+//	tx := Begin("com.example.app")
+//	tx.Set("config/server/host", "localhost")
+//	tx.Set("config/server/port", 8080)
+//	tx.Delete("legacy-key")
+//	err := tx.Commit()
+func Begin(appID string) *Tx {
+	return &Tx{
+		appID:   appID,
+		base:    make(map[string]any),
+		staged:  make(map[string]any),
+		removed: make(map[string]bool),
+	}
+}
+
+// AppID returns the application ID for the transaction.
+func (tx *Tx) AppID() string {
+	return tx.appID
+}
+
+// Set stages a preference value for the given keypath.
+func (tx *Tx) Set(keypath string, value any) (err error) {
+	defer wrapError(&err, "set", tx.appID, keypath)
+
+	kp, err := parseKeypath(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return tx.SetPath(kp, value)
+}
+
+// SetPath stages a preference value for the given KeyPath.
+func (tx *Tx) SetPath(kp KeyPath, value any) (err error) {
+	defer wrapError(&err, "set", tx.appID, kp.String())
+
+	value, err = prepareValue(tx.appID, kp, value)
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		return tx.DeletePath(kp)
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	op, err := tx.operation(kp, value)
+	if err != nil {
+		return err
+	}
+
+	// if there is no pointer, just stage the value
+	if kp.IsRoot() {
+		tx.stage(kp.Key(), value)
+		tx.ops = append(tx.ops, op)
+		return nil
+	}
+
+	root, exists, err := tx.root(kp.Key())
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		root = make(map[string]any)
+	}
+
+	modified, err := setValueAtPath(root, kp.tokens, value)
+	if err != nil {
+		return NewInternalError().Wrap(err).WithMsgF("failed to set: %s", kp.Pointer())
+	}
+
+	tx.stage(kp.Key(), modified)
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// Delete stages the removal of the value at the given keypath.
+func (tx *Tx) Delete(keypath string) (err error) {
+	defer wrapError(&err, "delete", tx.appID, keypath)
+
+	kp, err := parseKeypath(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return tx.DeletePath(kp)
+}
+
+// DeletePath stages the removal of the value at the given KeyPath.
+func (tx *Tx) DeletePath(kp KeyPath) (err error) {
+	defer wrapError(&err, "delete", tx.appID, kp.String())
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	op, err := tx.operation(kp, nil)
+	if err != nil {
+		return err
+	}
+
+	// if there is no pointer path, just remove the entire key
+	if kp.IsRoot() {
+		tx.stage(kp.Key(), nil)
+		tx.ops = append(tx.ops, op)
+		return nil
+	}
+
+	root, exists, err := tx.root(kp.Key())
+	if err != nil {
+		return err
+	}
+
+	// if key doesn't exist, there is nothing to delete (idempotent)
+	if !exists {
+		tx.ops = append(tx.ops, op)
+		return nil
+	}
+
+	modified, deleted, err := deleteValueAtPath(root, kp.tokens)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		tx.stage(kp.Key(), modified)
+	}
+
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// Commit writes all staged changes and synchronizes once. The transaction is
+// closed afterwards, even if the write fails.
+//
+// Each staged change passes through the write hooks and middleware for the
+// domain, in the order it was staged, with the old value as seen by the
+// transaction when it was staged. Nothing is written if any change is
+// rejected.
+func (tx *Tx) Commit() (err error) {
+	defer wrapError(&err, "commit", tx.appID, "")
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTxClosed
+	}
+	tx.closed = true
+
+	if len(tx.staged) == 0 && len(tx.removed) == 0 && len(tx.ops) == 0 {
+		return nil
+	}
+
+	remove := make([]string, 0, len(tx.removed))
+	for key := range tx.removed {
+		remove = append(remove, key)
+	}
+
+	return runWrites(context.Background(), tx.appID, tx.ops, func() error {
+		if len(tx.staged) == 0 && len(remove) == 0 {
+			return nil
+		}
+
+		if err := internal.SetMultiple(tx.appID, tx.staged, remove); err != nil {
+			return NewInternalError().Wrap(err).WithMsgF("failed to commit: %s", tx.appID)
+		}
+
+		return nil
+	})
+}
+
+// Rollback discards all staged changes and closes the transaction.
+func (tx *Tx) Rollback() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.closed = true
+	clear(tx.base)
+	clear(tx.staged)
+	clear(tx.removed)
+	tx.ops = nil
+}
+
+// operation returns the write operation for a change being staged. The old
+// value includes earlier staged changes, and is only read if there are hooks
+// or middleware for the domain.
+func (tx *Tx) operation(kp KeyPath, value any) (WriteOp, error) {
+	op := WriteOp{AppID: tx.appID, KeyPath: kp, New: value}
+
+	if !hasWriteHooks(tx.appID) {
+		return op, nil
+	}
+
+	root, exists, err := tx.root(kp.Key())
+	if err != nil {
+		return op, err
+	}
+
+	if exists {
+		op.Old = oldValue(root, kp)
+	}
+
+	return op, nil
+}
+
+// stage records the new value for a top-level key; nil removes the key.
+func (tx *Tx) stage(key string, value any) {
+	if value == nil {
+		delete(tx.staged, key)
+		tx.removed[key] = true
+		return
+	}
+
+	delete(tx.removed, key)
+	tx.staged[key] = value
+}
+
+// preload reads the current values of the given top-level keys, so later
+// nested changes do not need to read them individually. Values are read the
+// same way as root, so the base of a key does not depend on how it was first
+// used.
+func (tx *Tx) preload(keys []string) error {
+	for _, key := range keys {
+		if _, ok := tx.base[key]; ok {
+			continue
+		}
+
+		value, _, err := readRoot(context.Background(), tx.appID, key)
+		if err != nil {
+			return err
+		}
+
+		// missing keys are recorded as nil so they are not read again
+		tx.base[key] = value
+	}
+
+	return nil
+}
+
+// root returns the current value of a top-level key, including staged changes.
+func (tx *Tx) root(key string) (any, bool, error) {
+	if tx.removed[key] {
+		return nil, false, nil
+	}
+
+	if value, ok := tx.staged[key]; ok {
+		return value, true, nil
+	}
+
+	if value, ok := tx.base[key]; ok {
+		return value, value != nil, nil
+	}
+
+	value, exists, err := readRoot(context.Background(), tx.appID, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tx.base[key] = value
+	return value, exists, nil
+}
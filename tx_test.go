@@ -0,0 +1,87 @@
+package cfprefs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestTxCommit(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "tx-remove", "remove me")
+	defer cleanup()
+	defer Delete(appID, "tx-test")
+
+	tx := Begin(appID)
+
+	testutil.AssertNoError(t, tx.Set("tx-test/server/host", "localhost"), "stage host")
+	testutil.AssertNoError(t, tx.Set("tx-test/server/port", int64(8080)), "stage port")
+	testutil.AssertNoError(t, tx.Set("tx-test/items/~]", "first"), "stage append")
+	testutil.AssertNoError(t, tx.Delete("tx-test/server/host"), "stage nested delete")
+	testutil.AssertNoError(t, tx.Delete("tx-remove"), "stage root delete")
+
+	// nothing is written until the transaction is committed
+	assertKeyExists(t, appID, "tx-test", false)
+	assertKeyExists(t, appID, "tx-remove", true)
+
+	err := tx.Commit()
+	testutil.AssertNoError(t, err, "commit")
+
+	expected := map[string]any{
+		"server": map[string]any{"port": int64(8080)},
+		"items":  []any{"first"},
+	}
+
+	value, err := GetMap(appID, "tx-test")
+	testutil.AssertNoError(t, err, "get committed value")
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("expected %v, got %v", expected, value)
+	}
+
+	assertKeyExists(t, appID, "tx-remove", false)
+
+	// the transaction is closed after commit
+	if err := tx.Set("tx-test/other", "value"); !errors.Is(err, ErrTxClosed) {
+		t.Fatalf("expected ErrTxClosed, got %v", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrTxClosed) {
+		t.Fatalf("expected ErrTxClosed, got %v", err)
+	}
+}
+
+func TestTxMergesExisting(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "tx-merge", map[string]any{"name": "original", "count": int64(1)})
+	defer cleanup()
+
+	tx := Begin(appID)
+	testutil.AssertNoError(t, tx.Set("tx-merge/name", "updated"), "stage name")
+	testutil.AssertNoError(t, tx.Set("tx-merge/extra", true), "stage extra")
+	testutil.AssertNoError(t, tx.Commit(), "commit")
+
+	expected := map[string]any{"name": "updated", "count": int64(1), "extra": true}
+
+	value, err := GetMap(appID, "tx-merge")
+	testutil.AssertNoError(t, err, "get merged value")
+	if !reflect.DeepEqual(value, expected) {
+		t.Fatalf("expected %v, got %v", expected, value)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	tx := Begin(appID)
+	testutil.AssertNoError(t, tx.Set("tx-rollback", "value"), "stage value")
+	tx.Rollback()
+
+	if err := tx.Commit(); !errors.Is(err, ErrTxClosed) {
+		t.Fatalf("expected ErrTxClosed, got %v", err)
+	}
+
+	assertKeyExists(t, appID, "tx-rollback", false)
+}
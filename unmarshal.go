@@ -0,0 +1,427 @@
+package cfprefs
+
+import (
+	"context"
+	"encoding"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Unmarshal decodes the preference value at the given keypath into the value
+// pointed to by v.
+//
+// Dictionaries are decoded into structs using the same `plist` and `json` tag
+// conventions as Set, and into maps with string keys. Numbers are converted
+// to the width of the target, returning a RangeErr if they do not fit. Types
+// that implement encoding.TextUnmarshaler are decoded from strings, and types
+// with a registered converter are decoded using the converter.
+//
+// Example usage:
+//
+//	var server struct {
+//		Host string `plist:"host"`
+//		Port int    `plist:"port"`
+//	}
+//	err := Unmarshal("com.example.app", "config/server", &server)
+func Unmarshal(appID, keypath string, v any) (err error) {
+	defer wrapError(&err, "unmarshal", appID, keypath)
+
+	kp, err := parseKeypath(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return UnmarshalPath(appID, kp, v)
+}
+
+// UnmarshalPath decodes the preference value at the given KeyPath into the
+// value pointed to by v. See Unmarshal for the supported types.
+func UnmarshalPath(appID string, kp KeyPath, v any) (err error) {
+	defer wrapError(&err, "unmarshal", appID, kp.String())
+
+	rv, err := decodeTarget(appID, kp.String(), v)
+	if err != nil {
+		return err
+	}
+
+	value, err := GetPath(appID, kp)
+	if err != nil {
+		return err
+	}
+
+	return decodeValue(appID, kp, value, rv)
+}
+
+// Load decodes every key in the given appID into the struct or map pointed to
+// by v, using the top-level keys as field names. Keys without a matching field
+// are ignored. Values are read from the full domain hierarchy, like Get.
+//
+// Example usage:
+//
+//	var cfg struct {
+//		Username string    `plist:"username"`
+//		LastSeen time.Time `plist:"lastSeen"`
+//	}
+//	err := Load("com.example.app", &cfg)
+func Load(appID string, v any) (err error) {
+	defer wrapError(&err, "load", appID, "")
+
+	rv, err := decodeTarget(appID, "", v)
+	if err != nil {
+		return err
+	}
+
+	keys, err := GetKeys(appID)
+	if err != nil {
+		return NewInternalError().Wrap(err).WithMsgF("failed to get keys: %s", appID)
+	}
+
+	// fields may be set outside the current user's domain, so they are read
+	// even if they are not listed
+	keys = append(keys, fieldKeys(rv.Type())...)
+
+	// values are read the same way as Get, so Load and Unmarshal agree
+	values := make(map[string]any, len(keys))
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+
+		value, exists, err := readRoot(context.Background(), appID, key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			values[key] = value
+		}
+	}
+
+	return decodeValue(appID, KeyPath{}, values, rv)
+}
+
+// fieldKeys returns the top-level keys of the fields of a struct type, or of
+// the struct a pointer type refers to.
+func fieldKeys(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	for _, field := range structFields(t) {
+		keys = append(keys, field.name)
+	}
+	return keys
+}
+
+// decodeTarget checks that v is a non-nil pointer and returns its element.
+func decodeTarget(appID, key string, v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return reflect.Value{}, NewTypeMismatchError(nil, v).WithKey(appID, key)
+	}
+	return rv.Elem(), nil
+}
+
+// decodeValue stores a preference value in the target, converting it to the
+// type of the target.
+func decodeValue(appID string, kp KeyPath, value any, rv reflect.Value) error {
+	// a missing value leaves the target at its zero value
+	if value == nil {
+		rv.SetZero()
+		return nil
+	}
+
+	if conv, ok := lookupConverter(rv.Type()); ok {
+		if err := conv.decode(value, rv); err != nil {
+			return NewTypeMismatchError(rv.Interface(), value).WithKey(appID, kp.String()).Wrap(err)
+		}
+		return nil
+	}
+
+	// allocate pointers as needed and decode into their element
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(appID, kp, value, rv.Elem())
+	}
+
+	mismatch := func() error {
+		return NewTypeMismatchError(rv.Interface(), value).WithKey(appID, kp.String())
+	}
+
+	// dates are decoded directly, before checking for TextUnmarshaler
+	if date, ok := value.(time.Time); ok {
+		if rv.Type() != reflect.TypeFor[time.Time]() && rv.Kind() != reflect.Interface {
+			return mismatch()
+		}
+		rv.Set(reflect.ValueOf(date))
+		return nil
+	}
+
+	if rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			text, ok := value.(string)
+			if !ok {
+				return mismatch()
+			}
+			if err := u.UnmarshalText([]byte(text)); err != nil {
+				return NewTypeMismatchError(rv.Interface(), value).WithKey(appID, kp.String()).Wrap(err)
+			}
+			return nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() > 0 {
+			return mismatch()
+		}
+		rv.Set(reflect.ValueOf(value))
+		return nil
+
+	case reflect.String:
+		str, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetString(str)
+		return nil
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt64(value)
+		if !ok {
+			return mismatch()
+		}
+		if rv.OverflowInt(n) {
+			return NewRangeError(value).WithKey(appID, kp.String()).WithMsgF("does not fit in %s", rv.Type())
+		}
+		rv.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok, negative := toUint64(value)
+		if !ok {
+			return mismatch()
+		}
+		if negative || rv.OverflowUint(n) {
+			return NewRangeError(value).WithKey(appID, kp.String()).WithMsgF("does not fit in %s", rv.Type())
+		}
+		rv.SetUint(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat64(value)
+		if !ok {
+			return mismatch()
+		}
+		if rv.OverflowFloat(f) {
+			return NewRangeError(value).WithKey(appID, kp.String()).WithMsgF("does not fit in %s", rv.Type())
+		}
+		rv.SetFloat(f)
+		return nil
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data, ok := value.([]byte)
+			if !ok {
+				return mismatch()
+			}
+			rv.SetBytes(append([]byte{}, data...))
+			return nil
+		}
+
+		elems, ok := value.([]any)
+		if !ok {
+			return mismatch()
+		}
+
+		slice := reflect.MakeSlice(rv.Type(), len(elems), len(elems))
+		for idx, elem := range elems {
+			if err := decodeValue(appID, childPath(kp, idx), elem, slice.Index(idx)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+		return nil
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data, ok := value.([]byte)
+			if !ok || len(data) > rv.Len() {
+				return mismatch()
+			}
+			rv.SetZero()
+			reflect.Copy(rv, reflect.ValueOf(data))
+			return nil
+		}
+
+		elems, ok := value.([]any)
+		if !ok || len(elems) > rv.Len() {
+			return mismatch()
+		}
+
+		rv.SetZero()
+		for idx, elem := range elems {
+			if err := decodeValue(appID, childPath(kp, idx), elem, rv.Index(idx)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		fields, ok := value.(map[string]any)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(fields)))
+		}
+
+		for key, field := range fields {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(appID, childPath(kp, key), field, elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+		}
+		return nil
+
+	case reflect.Struct:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+
+		for _, field := range structFields(rv.Type()) {
+			data, exists := fields[field.name]
+			if !exists {
+				continue
+			}
+
+			fv, ok := fieldByIndexAlloc(rv, field.index)
+			if !ok {
+				continue
+			}
+
+			if err := decodeValue(appID, childPath(kp, field.name), data, fv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return mismatch()
+}
+
+// fieldByIndexAlloc returns the nested field of a struct, allocating any nil
+// embedded pointers along the way. Reports false if a nil embedded pointer
+// cannot be allocated because it is unexported.
+func fieldByIndexAlloc(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for depth, idx := range index {
+		if depth > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(idx)
+	}
+	return rv, true
+}
+
+// toInt64 converts a number to an int64. Reals are accepted if they hold an
+// integral value.
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float32:
+		return toInt64(float64(v))
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// toUint64 converts a number to a uint64, also reporting whether it was
+// negative. Values stored with the OverflowString and OverflowData policies
+// are decoded as well.
+func toUint64(value any) (uint64, bool, bool) {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v), true, false
+	case uint64:
+		return v, true, false
+	case string:
+		n, err := strconv.ParseUint(v, 10, 64)
+		return n, err == nil, false
+	case []byte:
+		if len(v) != 8 {
+			return 0, false, false
+		}
+		return binary.BigEndian.Uint64(v), true, false
+	case float32, float64:
+		f, _ := toFloat64(v)
+		if f != math.Trunc(f) || f >= math.MaxUint64 {
+			return 0, false, false
+		}
+		return uint64(max(f, 0)), true, f < 0
+	}
+
+	n, ok := toInt64(value)
+	return uint64(max(n, 0)), ok, n < 0
+}
+
+// toFloat64 converts a number to a float64.
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	n, ok := toInt64(value)
+	return float64(n), ok
+}
@@ -0,0 +1,205 @@
+package cfprefs
+
+import (
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+type unmarshalAuth struct {
+	User string `plist:"user"`
+}
+
+type unmarshalServer struct {
+	unmarshalAuth
+	Host    string         `plist:"host"`
+	Port    uint16         `plist:"port"`
+	Addr    net.IP         `plist:"addr"`
+	Tags    []string       `plist:"tags,omitempty"`
+	Limits  map[string]int `plist:"limits"`
+	Backup  *string        `plist:"backup"`
+	Ignored string         `plist:"-"`
+}
+
+type unmarshalConfig struct {
+	Server   unmarshalServer `plist:"server"`
+	Options  unmarshalOpts   `plist:",inline"`
+	Created  time.Time       `plist:"created"`
+	Blob     []byte          `plist:"blob"`
+	Ratio    float32         `plist:"ratio"`
+	Counters []int8          `plist:"counters"`
+}
+
+type unmarshalOpts struct {
+	Verbose bool `plist:"verbose"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cleanup := setupTest(t, appID, "unmarshal-test", map[string]any{
+		"server": map[string]any{
+			"user":   "admin",
+			"host":   "localhost",
+			"port":   int64(8080),
+			"addr":   "127.0.0.1",
+			"tags":   []any{"a", "b"},
+			"limits": map[string]any{"max": int64(10)},
+			"backup": "backup.example.com",
+		},
+		"verbose":  true,
+		"created":  created,
+		"blob":     []byte{0x01, 0x02},
+		"ratio":    0.5,
+		"counters": []any{int64(1), int64(-2)},
+		"unknown":  "ignored",
+	})
+	defer cleanup()
+
+	var cfg unmarshalConfig
+	err := Unmarshal(appID, "unmarshal-test", &cfg)
+	testutil.AssertNoError(t, err, "unmarshal config")
+
+	backup := "backup.example.com"
+	expected := unmarshalConfig{
+		Server: unmarshalServer{
+			unmarshalAuth: unmarshalAuth{User: "admin"},
+			Host:          "localhost",
+			Port:          8080,
+			Addr:          net.ParseIP("127.0.0.1"),
+			Tags:          []string{"a", "b"},
+			Limits:        map[string]int{"max": 10},
+			Backup:        &backup,
+		},
+		Options:  unmarshalOpts{Verbose: true},
+		Created:  created,
+		Blob:     []byte{0x01, 0x02},
+		Ratio:    0.5,
+		Counters: []int8{1, -2},
+	}
+
+	if !cfg.Created.Equal(expected.Created) {
+		t.Fatalf("expected %v, got %v", expected.Created, cfg.Created)
+	}
+	cfg.Created = expected.Created
+
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, cfg)
+	}
+
+	// decode a subtree into a scalar
+	var port int
+	err = Unmarshal(appID, "unmarshal-test/server/port", &port)
+	testutil.AssertNoError(t, err, "unmarshal scalar")
+	if port != 8080 {
+		t.Fatalf("expected 8080, got %d", port)
+	}
+
+	// values written by Set decode back into the same struct
+	err = Set(appID, "unmarshal-test", expected)
+	testutil.AssertNoError(t, err, "set struct")
+
+	var roundTrip unmarshalConfig
+	err = Unmarshal(appID, "unmarshal-test", &roundTrip)
+	testutil.AssertNoError(t, err, "unmarshal round trip")
+	roundTrip.Created = expected.Created
+
+	if !reflect.DeepEqual(roundTrip, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, roundTrip)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "unmarshal-errors", map[string]any{
+		"big":      int64(300),
+		"negative": int64(-1),
+		"name":     "text",
+		"counter":  "18446744073709551615",
+	})
+	defer cleanup()
+
+	var small int8
+	err := Unmarshal(appID, "unmarshal-errors/big", &small)
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+
+	var unsigned uint
+	err = Unmarshal(appID, "unmarshal-errors/negative", &unsigned)
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+
+	var target struct {
+		Name int `plist:"name"`
+	}
+	err = Unmarshal(appID, "unmarshal-errors", &target)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch, got %v", err)
+	}
+
+	var tmErr *TypeMismatchErr
+	if !errors.As(err, &tmErr) || tmErr.Key != "unmarshal-errors/name" {
+		t.Fatalf("expected keypath 'unmarshal-errors/name', got %v", err)
+	}
+
+	// large unsigned values stored with the string fallback are decoded
+	var counter uint64
+	err = Unmarshal(appID, "unmarshal-errors/counter", &counter)
+	testutil.AssertNoError(t, err, "unmarshal string fallback")
+	if counter != math.MaxUint64 {
+		t.Fatalf("expected %d, got %d", uint64(math.MaxUint64), counter)
+	}
+
+	err = Unmarshal(appID, "unmarshal-errors", target)
+	testutil.AssertError(t, err, "non-pointer target")
+
+	err = Unmarshal(appID, "unmarshal-errors/missing", &target)
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.load"
+
+	err := Set(appID, "username", "john")
+	testutil.AssertNoError(t, err, "set username")
+	defer Delete(appID, "username")
+
+	err = Set(appID, "settings", map[string]any{"theme": "dark"})
+	testutil.AssertNoError(t, err, "set settings")
+	defer Delete(appID, "settings")
+
+	var cfg struct {
+		Username string            `plist:"username"`
+		Settings map[string]string `plist:"settings"`
+		Missing  string            `plist:"missing"`
+	}
+
+	err = Load(appID, &cfg)
+	testutil.AssertNoError(t, err, "load domain")
+
+	if cfg.Username != "john" || cfg.Settings["theme"] != "dark" || cfg.Missing != "" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	var wrong struct {
+		Username int `plist:"username"`
+	}
+
+	err = Load(appID, &wrong)
+
+	var tmErr *TypeMismatchErr
+	if !errors.As(err, &tmErr) || tmErr.Key != "username" {
+		t.Fatalf("expected type mismatch for 'username', got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package cfprefs
+
+// Validator checks a value before it is written to a domain. It receives the
+// keypath being written and the value after conversion for storage, so
+// structs and other Go types have already become plain maps, slices and
+// scalars. A KeyPath without a key refers to the whole domain, as written by
+// Save.
+type Validator func(kp KeyPath, value any) error
+
+// validators holds the validators registered for each domain.
+var validators registry[Validator]
+
+// RegisterValidator adds a validator for the given appID, returning a
+// function that removes it. Every write operation runs the validators for its
+// domain in the order they were registered, and stops at the first error,
+// which is returned as a ValidationErr. Deleting a value is not validated.
+//
+// Example usage:
+//
+//	remove := cfprefs.RegisterValidator("com.example.app", func(kp cfprefs.KeyPath, v any) error {
+//		if kp.Key() == "locked" {
+//			return errors.New("locked is read-only")
+//		}
+//		return nil
+//	})
+//	defer remove()
+func RegisterValidator(appID string, fn Validator) (remove func()) {
+	if fn == nil {
+		panic("cfprefs: RegisterValidator with nil function")
+	}
+
+	return validators.add(appID, fn)
+}
+
+// validateWrite runs the validators registered for a domain on a value that
+// is about to be written.
+func validateWrite(appID string, kp KeyPath, value any) error {
+	for _, fn := range validators.get(appID) {
+		if err := fn(kp, value); err != nil {
+			return NewValidationError(value).WithKey(appID, kp.String()).Wrap(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package cfprefs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestRegisterValidator(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+	errLocked := errors.New("locked")
+
+	var seen []string
+	remove := RegisterValidator(appID, func(kp KeyPath, value any) error {
+		seen = append(seen, kp.String())
+		if kp.Key() == "validator-locked" {
+			return errLocked
+		}
+		return nil
+	})
+	defer remove()
+
+	t.Run("Accepted", func(t *testing.T) {
+		defer Delete(appID, "validator-test")
+
+		err := Set(appID, "validator-test/server/port", 8080)
+		testutil.AssertNoError(t, err, "set validated value")
+
+		if len(seen) == 0 || seen[len(seen)-1] != "validator-test/server/port" {
+			t.Fatalf("expected validator to see the keypath, got %v", seen)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		err := Set(appID, "validator-locked", "value")
+		if !errors.Is(err, ErrInvalidValue) || !errors.Is(err, errLocked) {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+
+		var valErr *ValidationErr
+		if !errors.As(err, &valErr) || valErr.Key != "validator-locked" || valErr.AppID != appID {
+			t.Fatalf("expected keypath in the error, got %v", err)
+		}
+
+		assertKeyExists(t, appID, "validator-locked", false)
+	})
+
+	t.Run("Rejected value", func(t *testing.T) {
+		ops := recordWrites(t, appID)
+
+		value, err := ValueOf(int16(3))
+		testutil.AssertNoError(t, err, "create value")
+
+		err = SetValue(appID, "validator-locked/level", value)
+		if !errors.Is(err, ErrInvalidValue) || !errors.Is(err, errLocked) {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+
+		assertKeyExists(t, appID, "validator-locked", false)
+
+		if len(*ops) != 0 {
+			t.Fatalf("expected no write hooks for a rejected value, got %+v", *ops)
+		}
+	})
+
+	t.Run("Other domain", func(t *testing.T) {
+		_, err := prepareValue("com.jheddings.cfprefs.other", Path("validator-locked"), "value")
+		testutil.AssertNoError(t, err, "prepare value in another domain")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := Delete(appID, "validator-locked")
+		testutil.AssertNoError(t, err, "delete is not validated")
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		remove()
+		remove()
+
+		_, err := prepareValue(appID, Path("validator-locked"), "value")
+		testutil.AssertNoError(t, err, "prepare value after removing validator")
+	})
+}
@@ -0,0 +1,557 @@
+package cfprefs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/internal"
+)
+
+// Kind identifies the storage type of a preference Value.
+type Kind int
+
+const (
+	// InvalidKind is the kind of the zero Value.
+	InvalidKind Kind = iota
+
+	// StringKind is a string value.
+	StringKind
+
+	// BoolKind is a boolean value.
+	BoolKind
+
+	// IntegerKind is a signed integer value with a width of 8, 16, 32 or 64 bits.
+	IntegerKind
+
+	// RealKind is a floating point value with a width of 32 or 64 bits.
+	RealKind
+
+	// DateKind is a point in time.
+	DateKind
+
+	// DataKind is a raw byte sequence.
+	DataKind
+
+	// ArrayKind is an ordered list of values.
+	ArrayKind
+
+	// DictionaryKind is a set of values indexed by string keys.
+	DictionaryKind
+)
+
+// kindNames maps each Kind to its name.
+var kindNames = map[Kind]string{
+	InvalidKind:    "invalid",
+	StringKind:     "string",
+	BoolKind:       "bool",
+	IntegerKind:    "integer",
+	RealKind:       "real",
+	DateKind:       "date",
+	DataKind:       "data",
+	ArrayKind:      "array",
+	DictionaryKind: "dictionary",
+}
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// kindOf returns the kind of a plain value, or InvalidKind if it is nil or
+// not a stored type.
+func kindOf(value any) Kind {
+	switch value.(type) {
+	case string:
+		return StringKind
+	case bool:
+		return BoolKind
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return IntegerKind
+	case float32, float64:
+		return RealKind
+	case time.Time:
+		return DateKind
+	case []byte:
+		return DataKind
+	case []any:
+		return ArrayKind
+	case map[string]any:
+		return DictionaryKind
+	}
+	return InvalidKind
+}
+
+// kindName returns the name of a kind, describing InvalidKind as missing.
+func kindName(kind Kind) string {
+	if kind == InvalidKind {
+		return "nothing"
+	}
+	return kind.String()
+}
+
+// parseKind returns the Kind with the given name.
+func parseKind(name string) (Kind, bool) {
+	for kind, kindName := range kindNames {
+		if kindName == name {
+			return kind, true
+		}
+	}
+	return InvalidKind, false
+}
+
+// Value is a preference value that records its exact storage type.
+//
+// Plain Go values lose information on the way through the preferences system:
+// a data value that contains a property list is read back as the decoded list,
+// and JSON output cannot tell a date from a string. A Value keeps the kind and
+// numeric width of the stored value, so it can be read and written back
+// without changing how it is stored.
+//
+// Use ValueOf to create a Value from a plain Go value and Interface to convert
+// it back. The zero Value is invalid.
+type Value struct {
+	kind  Kind
+	width int
+	data  any
+}
+
+// ValueOf creates a Value from a plain Go value.
+//
+// Integers keep their width, except for unsigned integers, which are widened
+// to the next signed type that holds their full range since the preferences
+// system only stores signed numbers. A uint or uint64 greater than
+// math.MaxInt64 returns a RangeErr. Slices and maps are converted
+// recursively; a Value is returned as-is.
+func ValueOf(value any) (Value, error) {
+	switch v := value.(type) {
+	case Value:
+		return v, nil
+
+	case string:
+		return Value{kind: StringKind, data: v}, nil
+
+	case bool:
+		return Value{kind: BoolKind, data: v}, nil
+
+	case int:
+		return intValue(int64(v), 64), nil
+	case int8:
+		return intValue(int64(v), 8), nil
+	case int16:
+		return intValue(int64(v), 16), nil
+	case int32:
+		return intValue(int64(v), 32), nil
+	case int64:
+		return intValue(v, 64), nil
+
+	case uint:
+		return uintValue(uint64(v), value)
+	case uint8:
+		return intValue(int64(v), 16), nil
+	case uint16:
+		return intValue(int64(v), 32), nil
+	case uint32:
+		return intValue(int64(v), 64), nil
+	case uint64:
+		return uintValue(v, value)
+
+	case float32:
+		return Value{kind: RealKind, width: 32, data: float64(v)}, nil
+	case float64:
+		return Value{kind: RealKind, width: 64, data: v}, nil
+
+	case time.Time:
+		return Value{kind: DateKind, data: v}, nil
+
+	case []byte:
+		return Value{kind: DataKind, data: v}, nil
+
+	case []any:
+		elems := make([]Value, len(v))
+		for idx, elem := range v {
+			converted, err := ValueOf(elem)
+			if err != nil {
+				return Value{}, err
+			}
+			elems[idx] = converted
+		}
+		return Value{kind: ArrayKind, data: elems}, nil
+
+	case []Value:
+		return Value{kind: ArrayKind, data: v}, nil
+
+	case map[string]any:
+		fields := make(map[string]Value, len(v))
+		for key, elem := range v {
+			converted, err := ValueOf(elem)
+			if err != nil {
+				return Value{}, err
+			}
+			fields[key] = converted
+		}
+		return Value{kind: DictionaryKind, data: fields}, nil
+
+	case map[string]Value:
+		return Value{kind: DictionaryKind, data: v}, nil
+	}
+
+	return Value{}, NewTypeMismatchError(Value{}, value)
+}
+
+// intValue creates an integer Value with the given width.
+func intValue(n int64, width int) Value {
+	return Value{kind: IntegerKind, width: width, data: n}
+}
+
+// uintValue creates an integer Value from an unsigned integer, which must fit
+// in a signed 64-bit integer.
+func uintValue(n uint64, original any) (Value, error) {
+	if n > math.MaxInt64 {
+		return Value{}, NewRangeError(original).WithMsg("exceeds the largest signed 64-bit integer")
+	}
+	return intValue(int64(n), 64), nil
+}
+
+// Kind returns the storage kind of the value.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Width returns the size in bits of an integer or real value, or 0 for all
+// other kinds.
+func (v Value) Width() int {
+	return v.width
+}
+
+// IsValid reports whether the value holds anything.
+func (v Value) IsValid() bool {
+	return v.kind != InvalidKind
+}
+
+// Array returns the elements of an array value, or nil for all other kinds.
+// The returned slice is shared with the value and must not be modified.
+func (v Value) Array() []Value {
+	elems, _ := v.data.([]Value)
+	return elems
+}
+
+// Dict returns the entries of a dictionary value, or nil for all other kinds.
+// The returned map is shared with the value and must not be modified.
+func (v Value) Dict() map[string]Value {
+	fields, _ := v.data.(map[string]Value)
+	return fields
+}
+
+// Interface converts the value to a plain Go value.
+//
+// Integers and reals are returned as the Go type matching their width (e.g.,
+// int16 or float32), so writing the result with Set stores the same type.
+// Arrays and dictionaries are converted recursively to []any and
+// map[string]any. The zero Value returns nil.
+func (v Value) Interface() any {
+	switch v.kind {
+	case IntegerKind:
+		n := v.data.(int64)
+		switch v.width {
+		case 8:
+			return int8(n)
+		case 16:
+			return int16(n)
+		case 32:
+			return int32(n)
+		}
+		return n
+
+	case RealKind:
+		f := v.data.(float64)
+		if v.width == 32 {
+			return float32(f)
+		}
+		return f
+
+	case ArrayKind:
+		elems := v.Array()
+		result := make([]any, len(elems))
+		for idx, elem := range elems {
+			result[idx] = elem.Interface()
+		}
+		return result
+
+	case DictionaryKind:
+		fields := v.Dict()
+		result := make(map[string]any, len(fields))
+		for key, elem := range fields {
+			result[key] = elem.Interface()
+		}
+		return result
+	}
+
+	return v.data
+}
+
+// String returns the value formatted with its default format.
+func (v Value) String() string {
+	if v.kind == DateKind {
+		return v.data.(time.Time).Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// jsonValue is the JSON encoding of a Value.
+type jsonValue struct {
+	Kind  string          `json:"kind"`
+	Width int             `json:"width,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON encodes the value as an object that records its kind and width
+// alongside the payload, e.g. {"kind":"integer","width":16,"value":42}.
+// Dates are encoded in RFC 3339 format and data as base64.
+func (v Value) MarshalJSON() ([]byte, error) {
+	if v.kind == InvalidKind {
+		return []byte("null"), nil
+	}
+
+	payload, err := json.Marshal(v.data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonValue{Kind: v.kind.String(), Width: v.width, Value: payload})
+}
+
+// UnmarshalJSON decodes a value encoded by MarshalJSON.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = Value{}
+		return nil
+	}
+
+	var encoded jsonValue
+	err := json.Unmarshal(data, &encoded)
+	if err != nil {
+		return err
+	}
+
+	kind, ok := parseKind(encoded.Kind)
+	if !ok || kind == InvalidKind {
+		return fmt.Errorf("unknown value kind: %q", encoded.Kind)
+	}
+
+	result := Value{kind: kind}
+
+	switch kind {
+	case StringKind:
+		result.data, err = decodePayload[string](encoded.Value)
+	case BoolKind:
+		result.data, err = decodePayload[bool](encoded.Value)
+	case IntegerKind:
+		result.width, err = checkWidth(kind, encoded.Width, 8, 16, 32, 64)
+		if err == nil {
+			result.data, err = decodePayload[int64](encoded.Value)
+		}
+	case RealKind:
+		result.width, err = checkWidth(kind, encoded.Width, 32, 64)
+		if err == nil {
+			result.data, err = decodePayload[float64](encoded.Value)
+		}
+	case DateKind:
+		result.data, err = decodePayload[time.Time](encoded.Value)
+	case DataKind:
+		result.data, err = decodePayload[[]byte](encoded.Value)
+	case ArrayKind:
+		result.data, err = decodePayload[[]Value](encoded.Value)
+	case DictionaryKind:
+		result.data, err = decodePayload[map[string]Value](encoded.Value)
+	}
+
+	if err == nil {
+		err = result.checkRange()
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %w", kind, err)
+	}
+
+	*v = result
+	return nil
+}
+
+// decodePayload decodes the JSON payload of a Value into the given type.
+func decodePayload[T any](data json.RawMessage) (any, error) {
+	var payload T
+	err := json.Unmarshal(data, &payload)
+	return payload, err
+}
+
+// checkWidth validates the width of a numeric kind, defaulting to 64 bits.
+func checkWidth(kind Kind, width int, allowed ...int) (int, error) {
+	if width == 0 {
+		return 64, nil
+	}
+	if !slices.Contains(allowed, width) {
+		return 0, fmt.Errorf("unsupported %s width: %d", kind, width)
+	}
+	return width, nil
+}
+
+// checkRange verifies that a numeric value fits in its width.
+func (v Value) checkRange() error {
+	switch v.kind {
+	case IntegerKind:
+		n := v.data.(int64)
+		limit := int64(1) << (v.width - 1)
+		if v.width < 64 && (n < -limit || n >= limit) {
+			return NewRangeError(n).WithMsgF("does not fit in %d bits", v.width)
+		}
+
+	case RealKind:
+		f := v.data.(float64)
+		if v.width == 32 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+			return NewRangeError(f).WithMsg("does not fit in 32 bits")
+		}
+	}
+
+	return nil
+}
+
+// GetValue retrieves a preference value for the given keypath, keeping its
+// exact storage type. Unlike Get, data values are never interpreted as
+// property lists or JSON.
+//
+// Example usage:
+//
+//	value, err := GetValue("com.example.app", "config/server/port")
+//	fmt.Println(value.Kind(), value.Width())
+func GetValue(appID, keypath string) (_ Value, err error) {
+	defer wrapError(&err, "get", appID, keypath)
+
+	kp, err := parseKeypath(keypath)
+	if err != nil {
+		return Value{}, NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return GetValuePath(appID, kp)
+}
+
+// GetValuePath retrieves a preference value for the given KeyPath, keeping its
+// exact storage type.
+func GetValuePath(appID string, kp KeyPath) (_ Value, err error) {
+	defer wrapError(&err, "get", appID, kp.String())
+
+	val, err := internal.GetRaw(appID, kp.Key())
+	if err != nil {
+		// only a failed lookup means the key is missing
+		if errors.Is(err, internal.ErrCFLookup) {
+			return Value{}, NewKeyNotFoundError(appID, kp.Key()).Wrap(err)
+		}
+		return Value{}, NewInternalError().Wrap(err).WithMsgF("failed to get: %s", kp.Key())
+	}
+
+	result, err := getValueAtPath(val, kp.tokens)
+	if err != nil {
+		return Value{}, resolveError(appID, kp, err)
+	}
+
+	return ValueOf(result)
+}
+
+// SetValue writes a preference value for the given keypath, storing it with
+// the exact kind and width recorded in the Value. Sibling values along a
+// nested keypath are also written back with their storage types unchanged.
+//
+// Example usage:
+//
+//	value, err := GetValue("com.example.app", "config")
+//	err = SetValue("com.example.app", "config", value)
+func SetValue(appID, keypath string, value Value) (err error) {
+	defer wrapError(&err, "set", appID, keypath)
+
+	kp, err := parseKeypath(keypath)
+	if err != nil {
+		return NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+	}
+
+	return SetValuePath(appID, kp, value)
+}
+
+// SetValuePath writes a preference value for the given KeyPath, storing it
+// with the exact kind and width recorded in the Value.
+//
+// Like Set, the value is checked against the nil policy and the registered
+// validators, and passes through the write hooks and middleware for the
+// domain.
+func SetValuePath(appID string, kp KeyPath, value Value) (err error) {
+	defer wrapError(&err, "set", appID, kp.String())
+
+	if !value.IsValid() {
+		return NewTypeMismatchError(Value{}, nil).WithKey(appID, kp.String())
+	}
+
+	// the Go types of a Value keep their widths when prepared
+	prepared, err := prepareValue(appID, kp, value.Interface())
+	if err != nil {
+		return err
+	}
+
+	op := WriteOp{AppID: appID, KeyPath: kp, New: prepared}
+	read := func() (any, bool, error) { return readRawRoot(appID, kp.Key()) }
+
+	return runWrite(context.Background(), op, read, func() error {
+		return storeRawValue(appID, kp, prepared)
+	})
+}
+
+// storeRawValue writes a prepared value for a KeyPath, leaving data values
+// along the path uninterpreted.
+func storeRawValue(appID string, kp KeyPath, value any) error {
+	// if there is no pointer, just set the value
+	if kp.IsRoot() {
+		return internal.Set(appID, kp.Key(), value)
+	}
+
+	// get or create the root value, leaving data values uninterpreted
+	root, exists, err := readRawRoot(appID, kp.Key())
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		root = make(map[string]any)
+	}
+
+	modified, err := setValueAtPath(root, kp.tokens, value)
+	if err != nil {
+		return NewInternalError().Wrap(err).WithMsgF("failed to set: %s", kp.Pointer())
+	}
+
+	return internal.Set(appID, kp.Key(), modified)
+}
+
+// readRawRoot reads the value of a top-level key without interpreting data
+// values, reporting whether it exists.
+func readRawRoot(appID, key string) (any, bool, error) {
+	exists, err := internal.Exists(appID, key)
+	if err != nil {
+		return nil, false, NewInternalError().Wrap(err).WithMsgF("failed to check: %s", key)
+	}
+
+	if !exists {
+		return nil, false, nil
+	}
+
+	value, err := internal.GetRaw(appID, key)
+	if err != nil {
+		return nil, false, NewInternalError().Wrap(err).WithMsgF("failed to get: %s", key)
+	}
+
+	return value, true, nil
+}
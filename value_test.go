@@ -0,0 +1,152 @@
+package cfprefs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+func TestValueOf(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value any
+		kind  Kind
+		width int
+		plain any
+	}{
+		{name: "string", value: "hello", kind: StringKind, plain: "hello"},
+		{name: "bool", value: true, kind: BoolKind, plain: true},
+		{name: "int8", value: int8(-8), kind: IntegerKind, width: 8, plain: int8(-8)},
+		{name: "int16", value: int16(-16), kind: IntegerKind, width: 16, plain: int16(-16)},
+		{name: "int32", value: int32(-32), kind: IntegerKind, width: 32, plain: int32(-32)},
+		{name: "int64", value: int64(-64), kind: IntegerKind, width: 64, plain: int64(-64)},
+		{name: "int", value: 42, kind: IntegerKind, width: 64, plain: int64(42)},
+		{name: "uint8", value: uint8(255), kind: IntegerKind, width: 16, plain: int16(255)},
+		{name: "uint16", value: uint16(65535), kind: IntegerKind, width: 32, plain: int32(65535)},
+		{name: "float32", value: float32(1.5), kind: RealKind, width: 32, plain: float32(1.5)},
+		{name: "float64", value: 2.5, kind: RealKind, width: 64, plain: 2.5},
+		{name: "data", value: []byte("raw"), kind: DataKind, plain: []byte("raw")},
+		{name: "array", value: []any{int8(1), "two"}, kind: ArrayKind, plain: []any{int8(1), "two"}},
+		{name: "dictionary", value: map[string]any{"port": int16(80)}, kind: DictionaryKind, plain: map[string]any{"port": int16(80)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := ValueOf(tc.value)
+			testutil.AssertNoError(t, err, "create value")
+
+			if value.Kind() != tc.kind {
+				t.Fatalf("expected kind %s, got %s", tc.kind, value.Kind())
+			}
+			if value.Width() != tc.width {
+				t.Fatalf("expected width %d, got %d", tc.width, value.Width())
+			}
+			if !reflect.DeepEqual(value.Interface(), tc.plain) {
+				t.Fatalf("expected %#v, got %#v", tc.plain, value.Interface())
+			}
+		})
+	}
+
+	_, err := ValueOf(make(chan int))
+	testutil.AssertError(t, err, "unsupported type")
+
+	if (Value{}).IsValid() {
+		t.Fatal("expected zero value to be invalid")
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	original, err := ValueOf(map[string]any{
+		"name":    "server",
+		"port":    int16(8080),
+		"ratio":   float32(0.5),
+		"created": date,
+		"blob":    []byte{0x00, 0x01, 0x02},
+		"tags":    []any{"a", int8(1)},
+	})
+	testutil.AssertNoError(t, err, "create value")
+
+	data, err := json.Marshal(original)
+	testutil.AssertNoError(t, err, "marshal value")
+
+	var decoded Value
+	err = json.Unmarshal(data, &decoded)
+	testutil.AssertNoError(t, err, "unmarshal value")
+
+	if !reflect.DeepEqual(decoded.Interface(), original.Interface()) {
+		t.Fatalf("expected %v, got %v", original.Interface(), decoded.Interface())
+	}
+
+	created := decoded.Dict()["created"]
+	if created.Kind() != DateKind {
+		t.Fatalf("expected date kind, got %s", created.Kind())
+	}
+
+	blob := decoded.Dict()["blob"]
+	if blob.Kind() != DataKind {
+		t.Fatalf("expected data kind, got %s", blob.Kind())
+	}
+
+	err = json.Unmarshal([]byte(`{"kind":"integer","width":12,"value":1}`), &decoded)
+	testutil.AssertError(t, err, "invalid width")
+
+	err = json.Unmarshal([]byte(`{"kind":"unknown","value":1}`), &decoded)
+	testutil.AssertError(t, err, "unknown kind")
+}
+
+func TestGetSetValue(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	original, err := ValueOf(map[string]any{
+		"small": int8(7),
+		"ratio": float32(0.25),
+		"blob":  []byte(`{"looks":"like json"}`),
+	})
+	testutil.AssertNoError(t, err, "create value")
+
+	err = SetValue(appID, "value-test", original)
+	testutil.AssertNoError(t, err, "set value")
+	defer Delete(appID, "value-test")
+
+	value, err := GetValue(appID, "value-test")
+	testutil.AssertNoError(t, err, "get value")
+	if !reflect.DeepEqual(value.Interface(), original.Interface()) {
+		t.Fatalf("expected %v, got %v", original.Interface(), value.Interface())
+	}
+
+	// data values are not interpreted when read as a Value
+	blob, err := GetValue(appID, "value-test/blob")
+	testutil.AssertNoError(t, err, "get data value")
+	if blob.Kind() != DataKind {
+		t.Fatalf("expected data kind, got %s", blob.Kind())
+	}
+
+	// nested writes keep the storage types of their siblings
+	port, err := ValueOf(int16(443))
+	testutil.AssertNoError(t, err, "create nested value")
+
+	err = SetValue(appID, "value-test/port", port)
+	testutil.AssertNoError(t, err, "set nested value")
+
+	value, err = GetValue(appID, "value-test")
+	testutil.AssertNoError(t, err, "get updated value")
+
+	fields := value.Dict()
+	if fields["port"].Width() != 16 || fields["small"].Width() != 8 {
+		t.Fatalf("expected widths to be preserved, got %v", fields)
+	}
+	if fields["blob"].Kind() != DataKind {
+		t.Fatalf("expected data kind to be preserved, got %s", fields["blob"].Kind())
+	}
+
+	err = SetValue(appID, "value-test", Value{})
+	testutil.AssertError(t, err, "invalid value")
+
+	_, err = GetValue(appID, "value-test/missing")
+	testutil.AssertError(t, err, "missing value")
+}
@@ -0,0 +1,172 @@
+package cfprefs
+
+import (
+	"context"
+	"iter"
+	"slices"
+)
+
+// walkConfig holds the options used when walking a preference domain.
+type walkConfig struct {
+	ctx        context.Context
+	maxDepth   int
+	containers bool
+	syntax     PathSyntax
+}
+
+// WalkOption configures the behavior of Walk.
+type WalkOption func(*walkConfig)
+
+// WithMaxDepth limits how far below each top-level key the walk descends.
+// A depth of 0 yields only the top-level values; a negative depth (the
+// default) walks the entire tree. Containers found at the limit are yielded
+// as a single value.
+func WithMaxDepth(depth int) WalkOption {
+	return func(cfg *walkConfig) {
+		cfg.maxDepth = depth
+	}
+}
+
+// WithContainers controls whether arrays and dictionaries are yielded in
+// addition to their children. Containers are yielded before their contents.
+func WithContainers(enabled bool) WalkOption {
+	return func(cfg *walkConfig) {
+		cfg.containers = enabled
+	}
+}
+
+// newWalkConfig creates a walk configuration with the given options applied.
+func newWalkConfig(ctx context.Context, opts ...WalkOption) *walkConfig {
+	cfg := &walkConfig{ctx: ctx, maxDepth: -1, syntax: GetPathSyntax()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Walk returns an iterator over every leaf value in the given appID.
+//
+// Each value is yielded with its full keypath, written in the current path
+// syntax (e.g., "config/servers/0/host" or "config.servers[0].host"). Keys
+// are escaped or quoted as needed, so the keypath may be passed directly back
+// to Get.
+//
+// Empty arrays and dictionaries are treated as leaves. Top-level keys are
+// visited in sorted order, as are the keys of nested dictionaries.
+//
+// Example usage:
+//
+//	for keypath, value := range Walk("com.example.app") {
+//		fmt.Printf("%s = %v\n", keypath, value)
+//	}
+//
+// A top-level key that cannot be read is yielded with the error as its value.
+// If the keys of the appID cannot be read at all, the error is yielded once
+// with an empty keypath.
+func Walk(appID string, opts ...WalkOption) iter.Seq2[string, any] {
+	return WalkContext(context.Background(), appID, opts...)
+}
+
+// WalkContext returns an iterator like Walk that stops early once ctx is done.
+// The context is checked before reading each top-level key and before
+// descending into each container; callers that need to distinguish a
+// completed walk from a canceled one should check ctx.Err() afterwards.
+func WalkContext(ctx context.Context, appID string, opts ...WalkOption) iter.Seq2[string, any] {
+	cfg := newWalkConfig(ctx, opts...)
+
+	return func(yield func(string, any) bool) {
+		keys, err := GetKeysContext(ctx, appID)
+		if err != nil {
+			if ctx.Err() == nil {
+				yield("", err)
+			}
+			return
+		}
+
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			if ctx.Err() != nil {
+				return
+			}
+
+			kp := Path(key)
+			keypath := kp.StringWith(cfg.syntax)
+
+			value, err := getPath(ctx, appID, kp)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				wrapError(&err, "get", appID, keypath)
+				if !yield(keypath, err) {
+					return
+				}
+				continue
+			}
+
+			visit := func(kp KeyPath, value any) bool {
+				return yield(kp.StringWith(cfg.syntax), value)
+			}
+
+			if !walkNode(kp, value, 0, cfg, visit) {
+				return
+			}
+		}
+	}
+}
+
+// walkNode yields the node at kp and descends into its children. Returns
+// false if the caller stopped the iteration.
+func walkNode(kp KeyPath, node any, depth int, cfg *walkConfig, yield func(KeyPath, any) bool) bool {
+	if cfg.ctx.Err() != nil {
+		return false
+	}
+
+	atLimit := cfg.maxDepth >= 0 && depth >= cfg.maxDepth
+
+	switch v := node.(type) {
+	case map[string]any:
+		if len(v) == 0 || atLimit {
+			return yield(kp, v)
+		}
+
+		if cfg.containers && !yield(kp, v) {
+			return false
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			if !walkNode(kp.Child(key), v[key], depth+1, cfg, yield) {
+				return false
+			}
+		}
+
+		return true
+
+	case []any:
+		if len(v) == 0 || atLimit {
+			return yield(kp, v)
+		}
+
+		if cfg.containers && !yield(kp, v) {
+			return false
+		}
+
+		for idx, elem := range v {
+			if !walkNode(kp.Child(idx), elem, depth+1, cfg, yield) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return yield(kp, node)
+}
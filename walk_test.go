@@ -0,0 +1,175 @@
+package cfprefs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+// collectWalk gathers the results of a walk into a map
+func collectWalk(appID string, opts ...WalkOption) map[string]any {
+	result := make(map[string]any)
+	for keypath, value := range Walk(appID, opts...) {
+		result[keypath] = value
+	}
+	return result
+}
+
+func TestWalkLeaves(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.walk"
+
+	testData := map[string]any{
+		"name":  "Jane Doe",
+		"a/b":   "slash",
+		"m~n":   "tilde",
+		"empty": map[string]any{},
+		"items": []any{"first", map[string]any{"id": int64(2)}},
+	}
+
+	cleanup := setupTest(t, appID, "walk-test", testData)
+	defer cleanup()
+
+	// the top-level key is escaped like the rest of the path
+	cleanup = setupTest(t, appID, "walk~1slash", "top")
+	defer cleanup()
+
+	expected := map[string]any{
+		"walk~1slash":          "top",
+		"walk-test/name":       "Jane Doe",
+		"walk-test/a~1b":       "slash",
+		"walk-test/m~0n":       "tilde",
+		"walk-test/empty":      map[string]any{},
+		"walk-test/items/0":    "first",
+		"walk-test/items/1/id": int64(2),
+	}
+
+	result := collectWalk(appID)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	// every yielded keypath should resolve back to the same value
+	for keypath, value := range result {
+		actual, err := Get(appID, keypath)
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", keypath, err)
+		}
+		if !reflect.DeepEqual(actual, value) {
+			t.Fatalf("value mismatch for %s: expected %v, got %v", keypath, value, actual)
+		}
+	}
+}
+
+func TestWalkDotSyntax(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.walk"
+
+	SetPathSyntax(DotSyntax)
+	defer SetPathSyntax(PointerSyntax)
+
+	testData := map[string]any{
+		"my.key": "dotted",
+		"items":  []any{"first"},
+	}
+
+	cleanup := setupTest(t, appID, "walk/dot", testData)
+	defer cleanup()
+
+	expected := map[string]any{
+		`walk/dot."my.key"`: "dotted",
+		"walk/dot.items[0]": "first",
+	}
+
+	result := collectWalk(appID)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	for keypath, value := range result {
+		actual, err := Get(appID, keypath)
+		testutil.AssertNoError(t, err, "get "+keypath)
+		if !reflect.DeepEqual(actual, value) {
+			t.Fatalf("value mismatch for %s: expected %v, got %v", keypath, value, actual)
+		}
+	}
+}
+
+func TestWalkOptions(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing.walk"
+
+	testData := map[string]any{
+		"server": map[string]any{
+			"host": "localhost",
+			"port": int64(8080),
+		},
+	}
+
+	cleanup := setupTest(t, appID, "walk-opts", testData)
+	defer cleanup()
+
+	t.Run("Max depth", func(t *testing.T) {
+		result := collectWalk(appID, WithMaxDepth(1))
+		expected := map[string]any{
+			"walk-opts/server": testData["server"],
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("Top level only", func(t *testing.T) {
+		result := collectWalk(appID, WithMaxDepth(0))
+		expected := map[string]any{
+			"walk-opts": testData,
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("Containers", func(t *testing.T) {
+		var keypaths []string
+		for keypath := range Walk(appID, WithContainers(true)) {
+			keypaths = append(keypaths, keypath)
+		}
+		expected := []string{"walk-opts", "walk-opts/server", "walk-opts/server/host", "walk-opts/server/port"}
+		if !reflect.DeepEqual(keypaths, expected) {
+			t.Fatalf("expected %v, got %v", expected, keypaths)
+		}
+	})
+
+	t.Run("Early exit", func(t *testing.T) {
+		count := 0
+		for range Walk(appID) {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Fatalf("expected iteration to stop after 1 value, got %d", count)
+		}
+	})
+}
+
+func TestWalkContext(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	cleanup := setupTest(t, appID, "walk-ctx", map[string]any{"a": int64(1), "b": int64(2)})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// canceling during the walk stops it before the next value
+	var keypaths []string
+	for keypath := range WalkContext(ctx, appID) {
+		keypaths = append(keypaths, keypath)
+		cancel()
+	}
+
+	if len(keypaths) != 1 {
+		t.Fatalf("expected walk to stop after 1 value, got %v", keypaths)
+	}
+
+	testutil.AssertError(t, ctx.Err(), "canceled context")
+}
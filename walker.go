@@ -1,6 +1,7 @@
 package cfprefs
 
 import (
+	"errors"
 	"strconv"
 )
 
@@ -28,6 +29,9 @@ type pathTokenHandler struct {
 // pointerWalker traverses JSON structures using JSON Pointer tokens.
 type pointerWalker struct {
 	handler *pathTokenHandler
+
+	// tokens is the full path being walked, used to describe failures
+	tokens []string
 }
 
 // newPointerWalker creates a new pointer walker with the specified handler.
@@ -37,6 +41,13 @@ func newPointerWalker(handler *pathTokenHandler) *pointerWalker {
 	}
 }
 
+// walkPath traverses the data structure from its root using the full list of
+// JSON Pointer tokens. Errors identify the failing token within the list.
+func (w *pointerWalker) walkPath(root any, tokens []string) (any, error) {
+	w.tokens = tokens
+	return w.walk(root, tokens)
+}
+
 // walk traverses the data structure using JSON Pointer tokens and calls appropriate handlers.
 func (w *pointerWalker) walk(node any, tokens []string) (any, error) {
 	// base case: no more tokens
@@ -70,14 +81,14 @@ func (w *pointerWalker) walkArrayAppend(node any, remaining []string) (any, erro
 		if w.handler.onMissingElement != nil {
 			new, err := w.handler.onMissingElement(ArrayAppendOp)
 			if err != nil {
-				return nil, err
+				return nil, w.locate(err, remaining, node, ArrayKind)
 			}
 			arr, ok = new.([]any)
 			if !ok {
 				return nil, NewInternalError().WithMsg("onMissing did not return an array for array append operation")
 			}
 		} else {
-			return nil, NewKeyPathError().WithMsg("cannot append to non-array value")
+			return nil, w.tokenError(remaining, node, ArrayKind).WithMsg("cannot append to non-array value")
 		}
 	}
 
@@ -93,12 +104,12 @@ func (w *pointerWalker) walkArrayIndex(node any, index int, remaining []string)
 	// ensure we have an array
 	arr, ok := node.([]any)
 	if !ok {
-		return nil, NewKeyPathError().WithMsg("cannot index non-array value")
+		return nil, w.tokenError(remaining, node, ArrayKind).WithMsg("cannot index non-array value")
 	}
 
 	// validate bounds
 	if index < 0 || index >= len(arr) {
-		return nil, NewKeyPathError().WithMsgF("array index out of bounds: %d (array length: %d)", index, len(arr))
+		return nil, w.tokenError(remaining, node, InvalidKind).WithMsgF("array index out of bounds: %d (array length: %d)", index, len(arr))
 	}
 
 	if w.handler.onArrayIndex != nil {
@@ -115,20 +126,20 @@ func (w *pointerWalker) walkObjectKey(node any, key string, remaining []string)
 	if !ok {
 		// if node is not nil and not an object, we can't traverse through it
 		if node != nil {
-			return nil, NewKeyPathError().WithMsg("cannot traverse through non-object value")
+			return nil, w.tokenError(remaining, node, DictionaryKind).WithMsg("cannot traverse through non-object value")
 		}
 		// node is nil, try to create an object
 		if w.handler.onMissingElement != nil {
 			new, err := w.handler.onMissingElement(key)
 			if err != nil {
-				return nil, err
+				return nil, w.locate(err, remaining, node, DictionaryKind)
 			}
 			obj, ok = new.(map[string]any)
 			if !ok {
 				return nil, NewInternalError().WithMsg("onMissing did not return an object for key operation")
 			}
 		} else {
-			return nil, NewKeyPathError().WithMsg("cannot create object at path")
+			return nil, w.tokenError(remaining, node, DictionaryKind).WithMsg("cannot create object at path")
 		}
 	}
 
@@ -138,3 +149,25 @@ func (w *pointerWalker) walkObjectKey(node any, key string, remaining []string)
 
 	return nil, NewInternalError().WithMsg("no handler for object key operation")
 }
+
+// tokenError creates a KeyPathErr for the token before the remaining tokens,
+// recording the kind of node found there and the kind the token requires.
+func (w *pointerWalker) tokenError(remaining []string, node any, expected Kind) *KeyPathErr {
+	err := NewKeyPathError().WithKinds(kindOf(node), expected)
+	if index := len(w.tokens) - len(remaining) - 1; index >= 0 && index < len(w.tokens) {
+		err.AtToken(w.tokens, index)
+	}
+	return err
+}
+
+// locate records the failing token on a KeyPathErr returned by a handler that
+// does not know its position in the path.
+func (w *pointerWalker) locate(err error, remaining []string, node any, expected Kind) error {
+	var kpErr *KeyPathErr
+	if errors.As(err, &kpErr) && kpErr.Index < 0 {
+		located := w.tokenError(remaining, node, expected)
+		kpErr.Index, kpErr.Token, kpErr.Prefix = located.Index, located.Token, located.Prefix
+		kpErr.Actual, kpErr.Expected = located.Actual, located.Expected
+	}
+	return err
+}
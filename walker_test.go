@@ -0,0 +1,311 @@
+package cfprefs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+// deepCopy returns a full copy of a value, including all nested containers
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, elem := range v {
+			result[key] = deepCopy(elem)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for idx, elem := range v {
+			result[idx] = deepCopy(elem)
+		}
+		return result
+	}
+	return value
+}
+
+// deleteInPlace deletes the value at a path by modifying its containers, as
+// the walker did before updates were copy-on-write. Returns the new root.
+func deleteInPlace(root any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	token, remaining := tokens[0], tokens[1:]
+
+	switch v := root.(type) {
+	case map[string]any:
+		if len(remaining) == 0 {
+			delete(v, token)
+			return v, nil
+		}
+		child, err := deleteInPlace(v[token], remaining)
+		if err != nil {
+			return nil, err
+		}
+		v[token] = child
+		return v, nil
+
+	case []any:
+		idx, err := strconv.Atoi(token)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return nil, fmt.Errorf("invalid index: %s", token)
+		}
+		if len(remaining) == 0 {
+			return slices.Delete(v, idx, idx+1), nil
+		}
+		child, err := deleteInPlace(v[idx], remaining)
+		if err != nil {
+			return nil, err
+		}
+		v[idx] = child
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("not a container: %T", root)
+}
+
+// newWalkerTestData creates a value with several wide branches
+func newWalkerTestData() map[string]any {
+	root := make(map[string]any)
+	for i := range 20 {
+		branch := make(map[string]any)
+		for j := range 20 {
+			branch[fmt.Sprintf("key-%d", j)] = []any{int64(i), int64(j), "value"}
+		}
+		root[fmt.Sprintf("branch-%d", i)] = branch
+	}
+	return root
+}
+
+func TestSetValueCopyOnWrite(t *testing.T) {
+	root := map[string]any{
+		"server": map[string]any{"host": "localhost"},
+		"items":  []any{"first", map[string]any{"id": int64(2)}},
+		"other":  map[string]any{"untouched": true},
+	}
+	original := deepCopy(root)
+
+	testCases := []struct {
+		name   string
+		tokens []string
+	}{
+		{name: "object key", tokens: []string{"server", "port"}},
+		{name: "array index", tokens: []string{"items", "0"}},
+		{name: "nested in array", tokens: []string{"items", "1", "id"}},
+		{name: "array append", tokens: []string{"items", ArrayAppendOp}},
+		{name: "new branch", tokens: []string{"created", ArrayAppendOp, "name"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			modified, err := setValueAtPath(root, tc.tokens, "new")
+			testutil.AssertNoError(t, err, "set value")
+
+			if !reflect.DeepEqual(root, original) {
+				t.Fatalf("original root was modified: %v", root)
+			}
+
+			// unchanged subtrees are shared with the original
+			other := modified.(map[string]any)["other"].(map[string]any)
+			if reflect.ValueOf(other).Pointer() != reflect.ValueOf(root["other"]).Pointer() {
+				t.Fatal("expected unchanged subtree to be shared")
+			}
+		})
+	}
+}
+
+func TestDeleteValueCopyOnWrite(t *testing.T) {
+	items := []any{"first", "second", "third"}
+	root := map[string]any{
+		"items":  items,
+		"server": map[string]any{"host": "localhost", "port": int64(8080)},
+	}
+	original := deepCopy(root)
+
+	modified, deleted, err := deleteValueAtPath(root, []string{"items", "0"})
+	testutil.AssertNoError(t, err, "delete array element")
+	if !deleted {
+		t.Fatal("expected array element to be deleted")
+	}
+
+	if !reflect.DeepEqual(root, original) {
+		t.Fatalf("original root was modified: %v", root)
+	}
+
+	expected := []any{"second", "third"}
+	if !reflect.DeepEqual(modified.(map[string]any)["items"], expected) {
+		t.Fatalf("expected %v, got %v", expected, modified.(map[string]any)["items"])
+	}
+
+	modified, deleted, err = deleteValueAtPath(root, []string{"server", "host"})
+	testutil.AssertNoError(t, err, "delete object key")
+	if !deleted {
+		t.Fatal("expected object key to be deleted")
+	}
+
+	if !reflect.DeepEqual(root, original) {
+		t.Fatalf("original root was modified: %v", root)
+	}
+
+	// a missing path returns the original root untouched
+	unchanged, deleted, err := deleteValueAtPath(root, []string{"server", "missing"})
+	testutil.AssertNoError(t, err, "delete missing key")
+	if deleted {
+		t.Fatal("expected nothing to be deleted")
+	}
+	if reflect.ValueOf(unchanged).Pointer() != reflect.ValueOf(root).Pointer() {
+		t.Fatal("expected original root to be returned")
+	}
+}
+
+func BenchmarkSetValueAtPath(b *testing.B) {
+	root := newWalkerTestData()
+	tokens := []string{"branch-10", "key-10", "1"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := setValueAtPath(root, tokens, "new"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetValueDeepCopy(b *testing.B) {
+	root := newWalkerTestData()
+	tokens := []string{"branch-10", "key-10", "1"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := setValueInPlace(deepCopy(root), tokens, "new"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeleteValueAtPath(b *testing.B) {
+	root := newWalkerTestData()
+	tokens := []string{"branch-10", "key-10", "1"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := deleteValueAtPath(root, tokens); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeleteValueDeepCopy(b *testing.B) {
+	root := newWalkerTestData()
+	tokens := []string{"branch-10", "key-10", "1"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := deleteInPlace(deepCopy(root), tokens); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestKeyPathErrSegment(t *testing.T) {
+	root := map[string]any{
+		"server": map[string]any{"host": "localhost"},
+		"items":  []any{"first", "second"},
+	}
+
+	testCases := []struct {
+		name     string
+		run      func() error
+		index    int
+		token    string
+		prefix   string
+		actual   Kind
+		expected Kind
+	}{
+		{
+			name:     "Set through string",
+			run:      func() error { _, err := setValueAtPath(root, []string{"server", "host", "port"}, int64(1)); return err },
+			index:    2,
+			token:    "port",
+			prefix:   "/server/host",
+			actual:   StringKind,
+			expected: DictionaryKind,
+		},
+		{
+			name:     "Set index on object",
+			run:      func() error { _, err := setValueAtPath(root, []string{"server", "0"}, int64(1)); return err },
+			index:    1,
+			token:    "0",
+			prefix:   "/server",
+			actual:   DictionaryKind,
+			expected: ArrayKind,
+		},
+		{
+			name:   "Set out of bounds",
+			run:    func() error { _, err := setValueAtPath(root, []string{"items", "5"}, "x"); return err },
+			index:  1,
+			token:  "5",
+			prefix: "/items",
+			actual: ArrayKind,
+		},
+		{
+			name:   "Get missing key",
+			run:    func() error { _, err := getValueAtPath(root, []string{"server", "port"}); return err },
+			index:  1,
+			token:  "port",
+			prefix: "/server",
+		},
+		{
+			name:   "Get out of bounds",
+			run:    func() error { _, err := getValueAtPath(root, []string{"items", "2"}); return err },
+			index:  1,
+			token:  "2",
+			prefix: "/items",
+		},
+		{
+			name:     "Get through string",
+			run:      func() error { _, err := getValueAtPath(root, []string{"items", "0", "name"}); return err },
+			index:    2,
+			token:    "name",
+			prefix:   "/items/0",
+			actual:   StringKind,
+			expected: DictionaryKind,
+		},
+		{
+			name:     "Delete through string",
+			run:      func() error { _, _, err := deleteValueAtPath(root, []string{"server", "host", "0"}); return err },
+			index:    2,
+			token:    "0",
+			prefix:   "/server/host",
+			actual:   StringKind,
+			expected: ArrayKind,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.run()
+			testutil.AssertError(t, err, tc.name)
+
+			var kpErr *KeyPathErr
+			if !errors.As(err, &kpErr) {
+				t.Fatalf("expected *KeyPathErr, got %T", err)
+			}
+
+			if kpErr.Index != tc.index || kpErr.Token != tc.token || kpErr.Prefix != tc.prefix {
+				t.Errorf("expected segment %d %q after %q, got %d %q after %q",
+					tc.index, tc.token, tc.prefix, kpErr.Index, kpErr.Token, kpErr.Prefix)
+			}
+
+			if tc.expected != InvalidKind && (kpErr.Actual != tc.actual || kpErr.Expected != tc.expected) {
+				t.Errorf("expected kinds %v/%v, got %v/%v", tc.actual, tc.expected, kpErr.Actual, kpErr.Expected)
+			}
+		})
+	}
+}
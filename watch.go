@@ -0,0 +1,315 @@
+package cfprefs
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/internal"
+)
+
+// watchInterval is how often watched domains are checked for changes.
+var watchInterval = time.Second
+
+// Change describes a watched value that changed.
+//
+// Old is nil if the value did not exist before the change, and New is nil if
+// the value was deleted. If the domain could not be read, Err is set and the
+// other fields describe the keypath, if any, that caused the error.
+type Change struct {
+	AppID   string
+	KeyPath string
+	Old     any
+	New     any
+	Err     error
+}
+
+// watchBackend provides the values of a domain to Watch, and signals when
+// they may have changed.
+type watchBackend interface {
+	// roots returns the values of the given top-level keys, or of every key
+	// if keys is nil. Keys that do not exist are omitted.
+	roots(ctx context.Context, appID string, keys []string) (map[string]any, error)
+
+	// notify returns a channel that receives a value whenever the domain may
+	// have changed. The channel is closed when ctx is done.
+	notify(ctx context.Context, appID string) <-chan struct{}
+}
+
+// defaultWatchBackend reads the preferences system.
+var defaultWatchBackend watchBackend = prefsBackend{}
+
+// Watch returns a channel that receives a Change each time one of the
+// watched values changes, until ctx is done. With no keypaths, every
+// top-level key of the domain is watched.
+//
+// The values present when Watch is called are the baseline, and are not sent.
+// A change to a nested keypath is reported when the value at that path
+// differs, so changes elsewhere in the same top-level key are not sent.
+// Several changes between checks are reported as a single change.
+//
+// Domains are checked every second, and sooner when the preferences file of
+// the domain is modified. Invalid keypaths are reported once as a Change with
+// Err set, and are not watched.
+//
+// Example usage:
+//
+//	for change := range cfprefs.Watch(ctx, "com.example.app", "config/server/port") {
+//		if change.Err != nil {
+//			log.Print(change.Err)
+//			continue
+//		}
+//		fmt.Printf("%s: %v -> %v\n", change.KeyPath, change.Old, change.New)
+//	}
+func Watch(ctx context.Context, appID string, keypaths ...string) <-chan Change {
+	return watch(ctx, defaultWatchBackend, appID, keypaths)
+}
+
+// watchedPath is a keypath being watched, with the name it is reported as.
+type watchedPath struct {
+	name string
+	kp   KeyPath
+}
+
+// watch implements Watch using the given backend. The baseline is read
+// before returning, so any later change is reported.
+func watch(ctx context.Context, backend watchBackend, appID string, keypaths []string) <-chan Change {
+	ch := make(chan Change)
+
+	// invalid keypaths are reported before any changes
+	var pending []Change
+
+	var paths []watchedPath
+	for _, keypath := range keypaths {
+		kp, err := parseKeypath(keypath)
+		if err != nil {
+			err = NewKeyPathError().Wrap(err).WithMsgF("invalid keypath: %s", keypath)
+			wrapError(&err, "watch", appID, keypath)
+			pending = append(pending, Change{AppID: appID, KeyPath: keypath, Err: err})
+			continue
+		}
+		paths = append(paths, watchedPath{name: keypath, kp: kp})
+	}
+
+	send := func(change Change) bool {
+		select {
+		case ch <- change:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if len(keypaths) > 0 && len(paths) == 0 {
+		go func() {
+			defer close(ch)
+			for _, change := range pending {
+				if !send(change) {
+					return
+				}
+			}
+		}()
+		return ch
+	}
+
+	// only the top-level keys of the watched paths are read
+	var keys []string
+	for _, path := range paths {
+		if !slices.Contains(keys, path.kp.Key()) {
+			keys = append(keys, path.kp.Key())
+		}
+	}
+
+	// read the current values, reporting a failure once until the domain can
+	// be read again
+	var last map[string]any
+	failed := false
+
+	check := func() bool {
+		roots, err := backend.roots(ctx, appID, keys)
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if err != nil {
+			if failed {
+				return true
+			}
+			failed = true
+			wrapError(&err, "watch", appID, "")
+			return send(Change{AppID: appID, Err: err})
+		}
+
+		failed = false
+		current := watchedValues(paths, roots)
+
+		if last != nil {
+			for _, change := range diffWatched(appID, last, current) {
+				if !send(change) {
+					return false
+				}
+			}
+		}
+
+		last = current
+		return true
+	}
+
+	roots, err := backend.roots(ctx, appID, keys)
+	if err == nil {
+		last = watchedValues(paths, roots)
+	}
+
+	notify := backend.notify(ctx, appID)
+
+	go func() {
+		defer close(ch)
+
+		for _, change := range pending {
+			if !send(change) {
+				return
+			}
+		}
+
+		// report a failure to read the baseline
+		if err != nil {
+			failed = true
+			wrapError(&err, "watch", appID, "")
+			if !send(Change{AppID: appID, Err: err}) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-notify:
+				if !ok {
+					return
+				}
+			}
+
+			if !check() {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// watchedValues returns the value of each watched path, or of each top-level
+// key if there are no paths. Missing values are omitted.
+func watchedValues(paths []watchedPath, roots map[string]any) map[string]any {
+	if len(paths) == 0 {
+		return roots
+	}
+
+	values := make(map[string]any, len(paths))
+	for _, path := range paths {
+		root, exists := roots[path.kp.Key()]
+		if !exists {
+			continue
+		}
+
+		value, err := getValueAtPath(root, path.kp.tokens)
+		if err == nil {
+			values[path.name] = value
+		}
+	}
+
+	return values
+}
+
+// diffWatched returns the changes between two sets of watched values, in
+// order of their keypaths.
+func diffWatched(appID string, last, current map[string]any) []Change {
+	names := slices.Collect(maps.Keys(last))
+	for name := range current {
+		if _, ok := last[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []Change
+	for _, name := range names {
+		old, hadOld := last[name]
+		value, hasNew := current[name]
+
+		if hadOld == hasNew && valuesEqual(old, value) {
+			continue
+		}
+
+		changes = append(changes, Change{AppID: appID, KeyPath: name, Old: old, New: value})
+	}
+
+	return changes
+}
+
+// prefsBackend watches domains in the preferences system, polling as a
+// baseline and checking early on file system events.
+type prefsBackend struct{}
+
+// roots implements watchBackend.
+func (prefsBackend) roots(ctx context.Context, appID string, keys []string) (map[string]any, error) {
+	// pick up changes made by other processes
+	if err := internal.Synchronize(appID); err != nil {
+		return nil, NewInternalError().Wrap(err).WithMsgF("failed to sync: %s", appID)
+	}
+
+	if keys == nil {
+		// an empty domain has no key list, which is not an error here
+		keys, _ = internal.GetKeys(appID)
+	}
+
+	values := make(map[string]any, len(keys))
+	for _, key := range keys {
+		value, exists, err := readRoot(ctx, appID, key)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			values[key] = value
+		}
+	}
+
+	return values, nil
+}
+
+// notify implements watchBackend.
+func (prefsBackend) notify(ctx context.Context, appID string) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	events := fileEvents(ctx, appID)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case _, ok := <-events:
+				if !ok {
+					// fall back to polling
+					events = nil
+					continue
+				}
+			}
+
+			// coalesce signals that arrive before the last one is handled
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,86 @@
+package cfprefs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// preferencesDir returns the directory holding the preferences files of the
+// current user.
+func preferencesDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Preferences"), nil
+}
+
+// fileEvents returns a channel that receives a value when the preferences
+// file of a domain is modified, including when it is replaced by a rename.
+// The preferences system writes files lazily, so events are only a hint that
+// a domain may have changed.
+//
+// The channel is closed when ctx is done, or right away if the file cannot
+// be watched.
+func fileEvents(ctx context.Context, appID string) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	dir, err := preferencesDir()
+	if err != nil {
+		close(ch)
+		return ch
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		close(ch)
+		return ch
+	}
+
+	// the directory is watched, since files are replaced rather than modified
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		close(ch)
+		return ch
+	}
+
+	name := appID + ".plist"
+
+	go func() {
+		defer close(ch)
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				// temporary files are named after the file they replace
+				if !strings.HasPrefix(filepath.Base(event.Name), name) {
+					continue
+				}
+
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return ch
+}
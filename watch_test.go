@@ -0,0 +1,263 @@
+package cfprefs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jheddings/go-cfprefs/testutil"
+)
+
+// memoryBackend is an in-memory domain for testing Watch
+type memoryBackend struct {
+	mu      sync.Mutex
+	values  map[string]any
+	err     error
+	changed chan struct{}
+}
+
+func newMemoryBackend(values map[string]any) *memoryBackend {
+	return &memoryBackend{values: values, changed: make(chan struct{}, 1)}
+}
+
+// update modifies the domain and signals the change
+func (m *memoryBackend) update(fn func(values map[string]any)) {
+	m.mu.Lock()
+	fn(m.values)
+	m.mu.Unlock()
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// fail makes reading the domain return err, or succeed again if err is nil
+func (m *memoryBackend) fail(err error) {
+	m.update(func(map[string]any) { m.err = err })
+}
+
+func (m *memoryBackend) roots(ctx context.Context, appID string, keys []string) (map[string]any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if keys == nil {
+		return maps.Clone(m.values), nil
+	}
+
+	values := make(map[string]any)
+	for _, key := range keys {
+		if value, ok := m.values[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (m *memoryBackend) notify(ctx context.Context, appID string) <-chan struct{} {
+	ch := make(chan struct{})
+
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.changed:
+			}
+			select {
+			case ch <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// receiveChange waits for the next change on a channel
+func receiveChange(t *testing.T, ctx context.Context, changes <-chan Change) Change {
+	t.Helper()
+
+	select {
+	case change, ok := <-changes:
+		if !ok {
+			t.Fatalf("channel closed while waiting for a change")
+		}
+		return change
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for a change")
+	}
+	return Change{}
+}
+
+func TestWatchKeyPaths(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	backend := newMemoryBackend(map[string]any{
+		"config": map[string]any{
+			"server": map[string]any{"host": "localhost", "port": int64(8080)},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := watch(ctx, backend, appID, []string{"config/server/port", "name"})
+
+	// changes to other values in the same root are not reported
+	backend.update(func(values map[string]any) {
+		values["config"] = map[string]any{
+			"server": map[string]any{"host": "example.com", "port": int64(8080)},
+		}
+	})
+
+	backend.update(func(values map[string]any) {
+		values["config"] = map[string]any{
+			"server": map[string]any{"host": "example.com", "port": int64(9090)},
+		}
+	})
+
+	change := receiveChange(t, ctx, changes)
+	expected := Change{AppID: appID, KeyPath: "config/server/port", Old: int64(8080), New: int64(9090)}
+	if !reflect.DeepEqual(change, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, change)
+	}
+
+	backend.update(func(values map[string]any) { values["name"] = "app" })
+
+	change = receiveChange(t, ctx, changes)
+	expected = Change{AppID: appID, KeyPath: "name", New: "app"}
+	if !reflect.DeepEqual(change, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, change)
+	}
+
+	backend.update(func(values map[string]any) { delete(values, "config") })
+
+	change = receiveChange(t, ctx, changes)
+	expected = Change{AppID: appID, KeyPath: "config/server/port", Old: int64(9090)}
+	if !reflect.DeepEqual(change, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, change)
+	}
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestWatchDomain(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	backend := newMemoryBackend(map[string]any{"a": "first", "b": int32(1)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := watch(ctx, backend, appID, nil)
+
+	// numbers are compared by value, so a new width is not a change
+	backend.update(func(values map[string]any) {
+		values["a"] = "second"
+		values["b"] = int64(1)
+		values["c"] = []any{"new"}
+	})
+
+	var received []Change
+	for range 2 {
+		received = append(received, receiveChange(t, ctx, changes))
+	}
+
+	expected := []Change{
+		{AppID: appID, KeyPath: "a", Old: "first", New: "second"},
+		{AppID: appID, KeyPath: "c", New: []any{"new"}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, received)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Invalid keypath", func(t *testing.T) {
+		backend := newMemoryBackend(map[string]any{})
+		changes := watch(ctx, backend, appID, []string{""})
+
+		change := receiveChange(t, ctx, changes)
+		if !errors.Is(change.Err, ErrInvalidKeyPath) {
+			t.Fatalf("expected ErrInvalidKeyPath, got %v", change.Err)
+		}
+
+		// nothing is left to watch
+		if _, ok := <-changes; ok {
+			t.Fatalf("expected the channel to be closed")
+		}
+	})
+
+	t.Run("Read failure", func(t *testing.T) {
+		backend := newMemoryBackend(map[string]any{"name": "app"})
+		changes := watch(ctx, backend, appID, []string{"name"})
+
+		errRead := errors.New("read failed")
+		backend.fail(errRead)
+
+		change := receiveChange(t, ctx, changes)
+		if !errors.Is(change.Err, errRead) {
+			t.Fatalf("expected read error, got %v", change.Err)
+		}
+
+		var perr *Error
+		if !errors.As(change.Err, &perr) || perr.Op != "watch" || perr.AppID != appID {
+			t.Fatalf("expected a watch error, got %v", change.Err)
+		}
+
+		// the failure is reported once, and values are compared again on recovery
+		backend.fail(errRead)
+		backend.update(func(map[string]any) { backend.err = nil })
+		backend.update(func(values map[string]any) { values["name"] = "updated" })
+
+		change = receiveChange(t, ctx, changes)
+		if change.Err != nil || change.New != "updated" {
+			t.Fatalf("expected the next change after recovering, got %+v", change)
+		}
+	})
+}
+
+func TestWatch(t *testing.T) {
+	appID := "com.jheddings.cfprefs.testing"
+
+	interval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	defer func() { watchInterval = interval }()
+
+	cleanup := setupTest(t, appID, "watch-test", map[string]any{"count": 1})
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := Watch(ctx, appID, "watch-test/count")
+
+	testutil.AssertNoError(t, Set(appID, "watch-test/count", 2), "set count")
+
+	change := receiveChange(t, ctx, changes)
+	if change.KeyPath != "watch-test/count" || !valuesEqual(change.Old, 1) || !valuesEqual(change.New, 2) {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+
+	cancel()
+	for range changes {
+	}
+}